@@ -0,0 +1,200 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+)
+
+// Names of the fields of aggregate panels, after the x field. Percentiles
+// follow, named after their rank, e.g. "p95".
+const (
+	aggregateCount  = "count"
+	aggregateMean   = "mean"
+	aggregateMedian = "median"
+	aggregateMin    = "min"
+	aggregateMax    = "max"
+	aggregateStd    = "std"
+)
+
+// getGroupModelMetrics returns the metrics of the processes of a group
+// aggregated across them: one panel per metric and step name, like
+// getModelMetrics, whose fields are the count, mean, median, min, max,
+// standard deviation and the percentiles listed in the percentiles query
+// parameter of the values of the processes at each step. The optional body
+// is a ModelMetricsQuery without process IDs, and the align query parameter
+// selects which processes have a value at each step.
+func (a *App) getGroupModelMetrics(tenantID string, req *http.Request) (interface{}, error) {
+	groupID, err := uuid.Parse(namedParam(req, "id"))
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+	query := req.URL.Query()
+	align, err := parseAlignMode(query.Get("align"))
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+	percentiles, err := parsePercentiles(query.Get("percentiles"))
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+	var metricsQuery ModelMetricsQuery
+	if len(bytes.TrimSpace(body)) > 0 {
+		metricsQuery, err = parseModelMetricsQuery(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if len(metricsQuery.ProcessIDs) > 0 {
+			return nil, middleware.ErrBadRequest(fmt.Errorf("process_ids cannot be set, the processes are the members of the group"))
+		}
+	}
+
+	db := a.db(req.Context())
+	if err := db.Where(&model.Group{TenantID: tenantID, ID: groupID}).First(&model.Group{}).Error; err != nil {
+		return nil, middleware.ErrNotFound(err)
+	}
+	err = db.Model(&model.Process{}).
+		Where("tenant_id = ? AND group_id = ?", tenantID, groupID).
+		Pluck("id", &metricsQuery.ProcessIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error finding group members: %w", err)
+	}
+
+	series, err := getStepSeries(req.Context(), db, tenantID, metricsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error getting step metrics: %w", err)
+	}
+
+	level.Info(a.logger).Log("msg", "aggregated group metrics", "tenantID", tenantID, "group_id", groupID, "processes", len(metricsQuery.ProcessIDs))
+	return aggregateStepSeries(series, align, percentiles), nil
+}
+
+// parsePercentiles parses a comma-separated list of percentiles, from 0 to
+// 100.
+func parsePercentiles(list string) ([]float64, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var percentiles []float64
+	for _, s := range strings.Split(list, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || !(p >= 0 && p <= 100) {
+			return nil, fmt.Errorf("percentiles must be numbers from 0 to 100, got %q", s)
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, nil
+}
+
+// aggregateStepSeries turns series positioned at their steps into one panel
+// per metric and step name, aggregating the series of each panel.
+func aggregateStepSeries(series []panelSeries, mode string, percentiles []float64) GetModelMetricsResponse {
+	return buildMetricsResponse(series, func(stepName string, series []metricSeries) DataFrame {
+		frame := alignSeries(Field{Name: stepName, Type: "number"}, series, mode, func(x float64) interface{} { return uint32(x) })
+		return aggregateFrame(frame, percentiles)
+	})
+}
+
+// aggregateFrame returns a frame with the same x field as the given one,
+// followed by the aggregates of its series at each position. NaN and
+// infinities are left out, so one diverging series doesn't hide the others;
+// the count tells how many values are aggregated. Aggregates of no values
+// are null, as is the standard deviation, corrected for the sample, of a
+// single value. Percentiles are interpolated linearly between the values.
+func aggregateFrame(frame DataFrame, percentiles []float64) DataFrame {
+	if len(frame) == 0 {
+		return frame
+	}
+	columns := make([][]*float64, len(frame)-1)
+	for i, field := range frame[1:] {
+		columns[i] = fieldNumbers(field)
+	}
+
+	rows := len(frame[0].Values)
+	newField := func(name string) Field {
+		return Field{Name: name, Type: "number", Values: make([]interface{}, 0, rows)}
+	}
+	count, mean, median := newField(aggregateCount), newField(aggregateMean), newField(aggregateMedian)
+	lo, hi, std := newField(aggregateMin), newField(aggregateMax), newField(aggregateStd)
+	ranks := make([]Field, len(percentiles))
+	for i, p := range percentiles {
+		ranks[i] = newField("p" + strconv.FormatFloat(p, 'f', -1, 64))
+	}
+
+	values := make([]float64, 0, len(columns))
+	for row := 0; row < rows; row++ {
+		values = values[:0]
+		for _, column := range columns {
+			if v := column[row]; v != nil && !math.IsNaN(*v) && !math.IsInf(*v, 0) {
+				values = append(values, *v)
+			}
+		}
+		n := float64(len(values))
+		count.Values = append(count.Values, n)
+		if len(values) == 0 {
+			for _, field := range []*Field{&mean, &median, &lo, &hi, &std} {
+				field.appendNumber(nil)
+			}
+			for i := range ranks {
+				ranks[i].appendNumber(nil)
+			}
+			continue
+		}
+		slices.Sort(values)
+
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		m := sum / n
+		mean.appendNumber(&m)
+		med := percentile(values, 50)
+		median.appendNumber(&med)
+		lo.appendNumber(&values[0])
+		hi.appendNumber(&values[len(values)-1])
+		if len(values) > 1 {
+			squares := 0.0
+			for _, v := range values {
+				squares += (v - m) * (v - m)
+			}
+			s := math.Sqrt(squares / (n - 1))
+			std.appendNumber(&s)
+		} else {
+			std.appendNumber(nil)
+		}
+		for i, p := range percentiles {
+			v := percentile(values, p)
+			ranks[i].appendNumber(&v)
+		}
+	}
+
+	out := DataFrame{frame[0], count, mean, median, lo, hi, std}
+	return append(out, ranks...)
+}
+
+// percentile returns the p-th percentile of sorted values, interpolating
+// linearly between the closest ranks.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	below := int(math.Floor(rank))
+	if below >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[below] + (sorted[below+1]-sorted[below])*(rank-float64(below))
+}
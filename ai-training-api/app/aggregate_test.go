@@ -0,0 +1,57 @@
+package api
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateFrame(t *testing.T) {
+	frame := DataFrame{{Name: "step", Type: "number", Values: []interface{}{uint32(1), uint32(2), uint32(3)}}}
+	one, two, four, six := 1.0, 2.0, 4.0, 6.0
+	inf := math.Inf(1)
+	for _, values := range [][]*float64{
+		{&one, &two, nil},
+		{&two, &inf, nil},
+		{&six, &four, &one},
+	} {
+		field := Field{Name: "process", Type: "number"}
+		for _, v := range values {
+			field.appendNumber(v)
+		}
+		frame = append(frame, field)
+	}
+
+	aggregated := aggregateFrame(frame, []float64{25, 97.5})
+	values := map[string][]interface{}{}
+	for _, field := range aggregated {
+		values[field.Name] = field.Values
+	}
+	require.Len(t, aggregated, 9)
+	assert.Equal(t, frame[0], aggregated[0])
+	// The diverging process is left out at step 2.
+	assert.Equal(t, map[string][]interface{}{
+		"step":   frame[0].Values,
+		"count":  {3.0, 2.0, 1.0},
+		"mean":   {3.0, 3.0, 1.0},
+		"median": {2.0, 3.0, 1.0},
+		"min":    {1.0, 2.0, 1.0},
+		"max":    {6.0, 4.0, 1.0},
+		"std":    {math.Sqrt(7), math.Sqrt(2), nil},
+		"p25":    {1.5, 2.5, 1.0},
+		"p97.5":  {5.8, 3.95, 1.0},
+	}, values)
+}
+
+func TestParsePercentiles(t *testing.T) {
+	percentiles, err := parsePercentiles("5, 50,99.9")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{5, 50, 99.9}, percentiles)
+
+	for _, list := range []string{"101", "-1", "median", "5,,95"} {
+		_, err := parsePercentiles(list)
+		assert.Error(t, err, list)
+	}
+}
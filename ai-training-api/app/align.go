@@ -0,0 +1,192 @@
+package api
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Modes of aligning the series of a panel on a shared x-axis, selected with
+// the align query parameter.
+const (
+	// Every position of any series. Series without a point at a position
+	// get a null value there.
+	alignUnion = "union"
+	// Only the positions every series has a point at.
+	alignIntersection = "intersection"
+	// Every position of any series, like alignUnion, but series are
+	// linearly interpolated at the positions within their range they have no
+	// point at.
+	alignInterpolate = "interpolate"
+)
+
+// parseAlignMode returns the alignment mode of a request, alignUnion by
+// default.
+func parseAlignMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return alignUnion, nil
+	case alignUnion, alignIntersection, alignInterpolate:
+		return mode, nil
+	}
+	return "", fmt.Errorf("align must be %q, %q or %q", alignUnion, alignIntersection, alignInterpolate)
+}
+
+// metricSeries is the points of a process in a panel, sorted by x, at most
+// one per position.
+type metricSeries struct {
+	processID uuid.UUID
+	xs        []float64
+	values    []*float64
+}
+
+// panelSeries is a series of the panel of a metric and step name.
+type panelSeries struct {
+	metricName string
+	stepName   string
+	metricSeries
+}
+
+// seriesOf groups results into series, in order of first appearance, with
+// position giving the position of a result on the x-axis. Results without a
+// position are dropped, and of the results of a series at the same position,
+// e.g. points of different steps logged within the same millisecond on a time
+// axis, the one of the latest step is kept, the last one breaking further
+// ties.
+func seriesOf(results []Result, position func(Result) (float64, bool)) []panelSeries {
+	type seriesKey struct {
+		metricName string
+		stepName   string
+		processID  uuid.UUID
+	}
+	index := map[seriesKey]int{}
+	var series []panelSeries
+	var steps [][]uint32
+	for _, r := range results {
+		x, ok := position(r)
+		if !ok {
+			continue
+		}
+		key := seriesKey{metricName: r.MetricName, stepName: r.StepName, processID: r.ProcessID}
+		i, ok := index[key]
+		if !ok {
+			i = len(series)
+			index[key] = i
+			series = append(series, panelSeries{
+				metricName:   r.MetricName,
+				stepName:     r.StepName,
+				metricSeries: metricSeries{processID: r.ProcessID},
+			})
+			steps = append(steps, nil)
+		}
+		series[i].xs = append(series[i].xs, x)
+		series[i].values = append(series[i].values, r.Value())
+		steps[i] = append(steps[i], r.Step)
+	}
+	for i := range series {
+		series[i].sort(steps[i])
+	}
+	return series
+}
+
+// sort sorts the points of a series by x, then by their steps, keeping the
+// last of the points at the same position.
+func (s *metricSeries) sort(steps []uint32) {
+	order := make([]int, len(s.xs))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		if c := cmp.Compare(s.xs[a], s.xs[b]); c != 0 {
+			return c
+		}
+		return cmp.Compare(steps[a], steps[b])
+	})
+
+	xs := make([]float64, 0, len(order))
+	values := make([]*float64, 0, len(order))
+	for _, i := range order {
+		if n := len(xs); n > 0 && xs[n-1] == s.xs[i] {
+			values[n-1] = s.values[i]
+			continue
+		}
+		xs = append(xs, s.xs[i])
+		values = append(values, s.values[i])
+	}
+	s.xs, s.values = xs, values
+}
+
+// alignSeries builds the data frame of a panel from its series, aligned on a
+// shared x-axis according to the mode. Each series is walked once, in step
+// with the positions of the axis. xValue converts positions to the values of
+// the x field.
+func alignSeries(xField Field, series []metricSeries, mode string, xValue func(float64) interface{}) DataFrame {
+	xs := alignedPositions(series, mode)
+	xField.Values = make([]interface{}, len(xs))
+	for i, x := range xs {
+		xField.Values[i] = xValue(x)
+	}
+
+	frame := DataFrame{xField}
+	for _, s := range series {
+		field := Field{Name: s.processID.String(), Type: "number", Values: make([]interface{}, 0, len(xs))}
+		j := 0
+		for _, x := range xs {
+			for j < len(s.xs) && s.xs[j] < x {
+				j++
+			}
+			switch {
+			case j < len(s.xs) && s.xs[j] == x:
+				field.appendNumber(s.values[j])
+			case mode == alignInterpolate && j > 0 && j < len(s.xs):
+				field.appendNumber(interpolate(s.xs[j-1], s.values[j-1], s.xs[j], s.values[j], x))
+			default:
+				field.appendNumber(nil)
+			}
+		}
+		frame = append(frame, field)
+	}
+	return frame
+}
+
+// alignedPositions returns the sorted positions of the x-axis of a panel:
+// those of any series, or of every series with alignIntersection.
+func alignedPositions(series []metricSeries, mode string) []float64 {
+	n := 0
+	for _, s := range series {
+		n += len(s.xs)
+	}
+	all := make([]float64, 0, n)
+	for _, s := range series {
+		all = append(all, s.xs...)
+	}
+	slices.Sort(all)
+
+	// Series have at most one point per position, so the number of times
+	// a position occurs is the number of series that have it.
+	xs := all[:0]
+	for i := 0; i < len(all); {
+		j := i + 1
+		for j < len(all) && all[j] == all[i] {
+			j++
+		}
+		if mode != alignIntersection || j-i == len(series) {
+			xs = append(xs, all[i])
+		}
+		i = j
+	}
+	return xs
+}
+
+// interpolate returns the value at x on the line between two points, or nil
+// if either of their values is null, NaN or infinite.
+func interpolate(x0 float64, y0 *float64, x1 float64, y1 *float64, x float64) *float64 {
+	if y0 == nil || y1 == nil || math.IsNaN(*y0) || math.IsInf(*y0, 0) || math.IsNaN(*y1) || math.IsInf(*y1, 0) {
+		return nil
+	}
+	y := *y0 + (*y1-*y0)*(x-x0)/(x1-x0)
+	return &y
+}
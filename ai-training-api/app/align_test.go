@@ -0,0 +1,104 @@
+package api
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlignSeries(t *testing.T) {
+	p1 := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	p2 := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	value := func(f float64) *float64 { return &f }
+	series := []metricSeries{
+		// Evaluated every other step, diverging at step 5.
+		{processID: p1, xs: []float64{1, 3, 5, 7}, values: []*float64{value(4), value(2), value(math.Inf(1)), value(0)}},
+		// Evaluated every step, from step 2.
+		{processID: p2, xs: []float64{2, 3, 4, 5}, values: []*float64{value(8), value(6), value(4), value(2)}},
+	}
+	xValue := func(x float64) interface{} { return uint32(x) }
+
+	tests := map[string]struct {
+		steps  []interface{}
+		p1, p2 []interface{}
+		inf    []int
+	}{
+		alignUnion: {
+			steps: []interface{}{uint32(1), uint32(2), uint32(3), uint32(4), uint32(5), uint32(7)},
+			p1:    []interface{}{4.0, nil, 2.0, nil, nil, 0.0},
+			p2:    []interface{}{nil, 8.0, 6.0, 4.0, 2.0, nil},
+			inf:   []int{4},
+		},
+		alignIntersection: {
+			steps: []interface{}{uint32(3), uint32(5)},
+			p1:    []interface{}{2.0, nil},
+			p2:    []interface{}{6.0, 2.0},
+			inf:   []int{1},
+		},
+		// Series are only interpolated within their range, and not next to
+		// infinities.
+		alignInterpolate: {
+			steps: []interface{}{uint32(1), uint32(2), uint32(3), uint32(4), uint32(5), uint32(7)},
+			p1:    []interface{}{4.0, 3.0, 2.0, nil, nil, 0.0},
+			p2:    []interface{}{nil, 8.0, 6.0, 4.0, 2.0, nil},
+			inf:   []int{4},
+		},
+	}
+	for mode, want := range tests {
+		t.Run(mode, func(t *testing.T) {
+			frame := alignSeries(Field{Name: "step", Type: "number"}, series, mode, xValue)
+			require.Len(t, frame, 3)
+			assert.Equal(t, want.steps, frame[0].Values)
+			assert.Equal(t, p1.String(), frame[1].Name)
+			assert.Equal(t, want.p1, frame[1].Values)
+			assert.Equal(t, &FieldEntities{Inf: want.inf}, frame[1].Entities)
+			assert.Equal(t, want.p2, frame[2].Values)
+		})
+	}
+}
+
+func TestSeriesOfKeepsLastPointPerPosition(t *testing.T) {
+	processID := uuid.New()
+	x := func(f float64) *float64 { return &f }
+	results := []Result{
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 2, MetricValue: x(1), X: x(0.5)},
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 1, MetricValue: x(2), X: x(0.25)},
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 3, MetricValue: x(3), X: x(0.5)},
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 4, MetricValue: x(4)},
+	}
+	series := seriesOf(results, func(r Result) (float64, bool) {
+		if r.X == nil {
+			return 0, false
+		}
+		return *r.X, true
+	})
+	require.Len(t, series, 1)
+	assert.Equal(t, []float64{0.25, 0.5}, series[0].xs)
+	assert.Equal(t, []*float64{x(2), x(3)}, series[0].values)
+}
+
+func TestSeriesOfKeepsLatestStepAtSameTime(t *testing.T) {
+	processID := uuid.New()
+	v := func(f float64) *float64 { return &f }
+	at := func(ms int64) *time.Time {
+		t := time.UnixMilli(ms)
+		return &t
+	}
+	// Points of different steps logged within the same millisecond, read in
+	// an arbitrary order as the query only orders them by time.
+	results := []Result{
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 2, MetricValue: v(2), Timestamp: at(1000)},
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 3, MetricValue: v(3), Timestamp: at(1000)},
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 1, MetricValue: v(1), Timestamp: at(1000)},
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 4, MetricValue: v(4), Timestamp: at(2000)},
+	}
+	response := transformMetricsDataByTime(results, xAxisTime, alignUnion)
+	require.Len(t, response.Sections["default"], 1)
+	frame := response.Sections["default"][0].Series
+	assert.Equal(t, []interface{}{int64(1000), int64(2000)}, frame[0].Values)
+	assert.Equal(t, []interface{}{float64(3), float64(4)}, frame[1].Values)
+}
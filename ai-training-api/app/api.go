@@ -1,26 +1,35 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/go-kit/log/level"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	flatten "github.com/jeremywohl/flatten/v2"
 	"gorm.io/gorm"
 
+	"github.com/grafana/ai-training-o11y/ai-training-api/filter"
+	"github.com/grafana/ai-training-o11y/ai-training-api/jsonpatch"
 	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
 	"github.com/grafana/ai-training-o11y/ai-training-api/model"
 )
 
 const (
-	listProcessLimit = 100
-	limitGroupLimit  = 10
+	listProcessLimit    = 100
+	maxListProcessLimit = 1000
+	limitGroupLimit     = 10
 )
 
 // RegisterAPI registers all routes to the router.
@@ -32,141 +41,157 @@ func (app *App) registerAPI(router *mux.Router) {
 	router.HandleFunc("/process/{id}/delete", requestMiddleware(app.deleteProcess)).Methods("POST")
 	router.HandleFunc("/processes", requestMiddleware(app.listProcess)).Methods("GET")
 	router.HandleFunc("/processes/model-metrics", requestMiddleware(app.getModelMetrics)).Methods("POST")
+	router.HandleFunc("/processes/metric-summaries", requestMiddleware(app.getMetricSummaries)).Methods("POST")
 	router.HandleFunc("/process/{id}/update-metadata", requestMiddleware(app.updateProcessMetadata)).Methods("POST")
+	router.HandleFunc("/process/{id}/metadata/history", requestMiddleware(app.getMetadataHistory)).Methods("GET")
+	router.HandleFunc("/process/{id}/state", requestMiddleware(app.updateProcessState)).Methods("POST")
+	router.HandleFunc("/process/{id}/heartbeat", requestMiddleware(app.processHeartbeat)).Methods("POST")
 	router.HandleFunc("/process/{id}/model-metrics", requestMiddleware(app.addModelMetrics)).Methods("POST")
+	router.HandleFunc("/write", requestMiddleware(app.remoteWrite)).Methods("POST")
+	router.HandleFunc("/metadata/keys", requestMiddleware(app.getMetadataKeys)).Methods("GET")
 	router.HandleFunc("/group/new", requestMiddleware(app.registerNewGroup)).Methods("POST")
 	router.HandleFunc("/group/{id}", requestMiddleware(app.getGroup)).Methods("GET")
 	router.HandleFunc("/groups", requestMiddleware(app.getGroups)).Methods("GET")
+	router.HandleFunc("/group/{id}/update", requestMiddleware(app.updateGroup)).Methods("POST")
+	router.HandleFunc("/group/{id}/add-members", requestMiddleware(app.addGroupMembers)).Methods("POST")
+	router.HandleFunc("/group/{id}/remove-members", requestMiddleware(app.removeGroupMembers)).Methods("POST")
 	router.HandleFunc("/group/{id}/delete", requestMiddleware(app.deleteGroup)).Methods("POST")
+	router.HandleFunc("/group/{id}/model-metrics", requestMiddleware(app.getGroupModelMetrics)).Methods("POST")
 }
 
 // registerNewProcess registers a new Process and returns a UUID.
 func (a *App) registerNewProcess(tenantID string, req *http.Request) (interface{}, error) {
-	// Register a new process.
-	process := &model.Process{}
-	process.ID = uuid.New()
-	level.Debug(a.logger).Log("msg", "generated new UUID", "process_id", process.ID, "uuid_length", len(process.ID.String()))
-
-	process.TenantID = tenantID
-	process.StartTime = time.Now()
-	process.Status = "running"
-
-	// Store process in DB.
-	level.Debug(a.logger).Log("msg", "attempting to create process", "process_id", process.ID, "tenant_id", tenantID)
-	err := a.db(req.Context()).Model(&model.Process{}).Create(process).Error
-	if err != nil {
-		level.Error(a.logger).Log("msg", "failed to create process", "process_id", process.ID, "error", err)
-		return nil, fmt.Errorf("error creating process: %w", err)
-	}
-	level.Debug(a.logger).Log("msg", "created process in DB", "process_id", process.ID)
-
 	// Read and parse request body.
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
-		level.Error(a.logger).Log("msg", "failed to read request body", "process_id", process.ID, "error", err)
+		level.Error(a.logger).Log("msg", "failed to read request body", "error", err)
 		return nil, middleware.ErrBadRequest(err)
 	}
 	defer req.Body.Close()
 
-	level.Debug(a.logger).Log("msg", "request body read", "process_id", process.ID, "body_length", len(body))
+	level.Debug(a.logger).Log("msg", "request body read", "body_length", len(body))
 
 	var data = map[string]interface{}{}
-	err = json.Unmarshal(body, &data)
+	err = decodeJSONWithNumbers(body, &data)
 	if err != nil {
-		level.Error(a.logger).Log("msg", "failed to unmarshal request body", "process_id", process.ID, "error", err)
+		level.Error(a.logger).Log("msg", "failed to unmarshal request body", "error", err)
 		return nil, middleware.ErrBadRequest(err)
 	}
 
-	level.Debug(a.logger).Log("msg", "parsed request body", "process_id", process.ID, "keys", fmt.Sprintf("%v", keys(data)))
+	level.Debug(a.logger).Log("msg", "parsed request body", "keys", fmt.Sprintf("%v", keys(data)))
 
 	// Process each field
+	var project, groupName string
+	var metadata map[string]interface{}
 	for key, value := range data {
-		level.Debug(a.logger).Log("msg", "processing field", "process_id", process.ID, "key", key, "value_type", fmt.Sprintf("%T", value))
+		level.Debug(a.logger).Log("msg", "processing field", "key", key, "value_type", fmt.Sprintf("%T", value))
 
+		var ok bool
 		switch key {
 		case "project":
-			process.Project = value.(string)
-			level.Debug(a.logger).Log("msg", "set project", "process_id", process.ID, "project", process.Project)
-
+			project, ok = value.(string)
 		case "group":
-			groupName := value.(string)
-			level.Debug(a.logger).Log("msg", "processing group", "process_id", process.ID, "group_name", groupName)
+			groupName, ok = value.(string)
+		case "user_metadata":
+			metadata, ok = value.(map[string]interface{})
+		default:
+			level.Error(a.logger).Log("msg", "unknown key in request body", "key", key)
+			continue
+		}
+		if !ok {
+			return nil, middleware.ErrBadRequest(fmt.Errorf("invalid %s", key))
+		}
+	}
 
-			var group model.Group
-			err = a.db(req.Context()).
-				Where(&model.Group{
-					TenantID: tenantID,
-					Name:     groupName,
-				}).First(&group).Error
-
-			if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
-				level.Debug(a.logger).Log("msg", "creating new group", "process_id", process.ID, "group_name", groupName)
-				groupID := uuid.New()
-				err = a.db(req.Context()).
-					Model(&model.Group{}).
-					Create(&model.Group{
-						TenantID: tenantID,
-						ID:       groupID,
-						Name:     value.(string),
-					}).Error
-				if err != nil {
-					level.Error(a.logger).Log("msg", "failed to create group", "process_id", process.ID, "error", err)
-					return nil, fmt.Errorf("error creating group: %w", err)
-				}
-				process.GroupID = &groupID
-				level.Debug(a.logger).Log("msg", "created new group", "process_id", process.ID, "group_id", groupID)
-			} else {
-				process.GroupID = &group.ID
-				level.Debug(a.logger).Log("msg", "using existing group", "process_id", process.ID, "group_id", group.ID)
-			}
+	return a.createProcess(req.Context(), tenantID, project, groupName, metadata)
+}
 
-		case "user_metadata":
-			metadata := value.(map[string]interface{})
-			level.Debug(a.logger).Log("msg", "processing metadata", "process_id", process.ID, "metadata_keys", len(metadata))
+// createProcess registers a new running process of the tenant. The group is
+// looked up by name and created if it doesn't exist yet.
+func (a *App) createProcess(ctx context.Context, tenantID, project, groupName string, metadata map[string]interface{}) (*model.Process, error) {
+	process := &model.Process{}
+	process.ID = uuid.New()
+	level.Debug(a.logger).Log("msg", "generated new UUID", "process_id", process.ID, "uuid_length", len(process.ID.String()))
 
-			dataMap, err := flatten.Flatten(metadata, "", flatten.DotStyle)
+	process.TenantID = tenantID
+	process.Project = project
+	process.StartTime = time.Now()
+	process.Status = model.ProcessStatusRunning
+
+	// Store process in DB.
+	level.Debug(a.logger).Log("msg", "attempting to create process", "process_id", process.ID, "tenant_id", tenantID)
+	err := a.db(ctx).Model(&model.Process{}).Create(process).Error
+	if err != nil {
+		level.Error(a.logger).Log("msg", "failed to create process", "process_id", process.ID, "error", err)
+		return nil, fmt.Errorf("error creating process: %w", err)
+	}
+	level.Debug(a.logger).Log("msg", "created process in DB", "process_id", process.ID)
+
+	if groupName != "" {
+		level.Debug(a.logger).Log("msg", "processing group", "process_id", process.ID, "group_name", groupName)
+
+		var group model.Group
+		err = a.db(ctx).
+			Where(&model.Group{
+				TenantID: tenantID,
+				Name:     groupName,
+			}).First(&group).Error
+
+		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+			level.Debug(a.logger).Log("msg", "creating new group", "process_id", process.ID, "group_name", groupName)
+			groupID := uuid.New()
+			err = a.db(ctx).
+				Model(&model.Group{}).
+				Create(&model.Group{
+					TenantID: tenantID,
+					ID:       groupID,
+					Name:     groupName,
+				}).Error
 			if err != nil {
-				level.Error(a.logger).Log("msg", "failed to flatten metadata", "process_id", process.ID, "error", err)
-				return nil, fmt.Errorf("error flattening metadata: %w", err)
+				level.Error(a.logger).Log("msg", "failed to create group", "process_id", process.ID, "error", err)
+				return nil, fmt.Errorf("error creating group: %w", err)
 			}
+			process.GroupID = &groupID
+			level.Debug(a.logger).Log("msg", "created new group", "process_id", process.ID, "group_id", groupID)
+		} else {
+			process.GroupID = &group.ID
+			level.Debug(a.logger).Log("msg", "using existing group", "process_id", process.ID, "group_id", group.ID)
+		}
+	}
 
-			level.Debug(a.logger).Log("msg", "flattened metadata", "process_id", process.ID, "flattened_keys", len(dataMap))
-
-			for mk, mv := range dataMap {
-				valueType, valueBytes := model.MarshalMetadataValue(mv)
-				level.Debug(a.logger).Log("msg", "creating metadata entry", "process_id", process.ID,
-					"key", mk, "value_type", valueType)
-
-				err = a.db(req.Context()).
-					Model(&model.MetadataKV{}).
-					Create(&model.MetadataKV{
-						TenantID:  tenantID,
-						Key:       mk,
-						Value:     valueBytes,
-						Type:      valueType,
-						ProcessID: process.ID,
-					}).Error
-				if err != nil {
-					level.Error(a.logger).Log("msg", "failed to create metadata", "process_id", process.ID,
-						"key", mk, "error", err)
-					return nil, fmt.Errorf("error creating metadata: %w", err)
-				}
-			}
+	if metadata != nil {
+		level.Debug(a.logger).Log("msg", "processing metadata", "process_id", process.ID, "metadata_keys", len(metadata))
 
-		default:
-			level.Error(a.logger).Log("msg", "unknown key in request body", "process_id", process.ID, "key", key)
+		dataMap := model.FlattenMetadata(metadata)
+		level.Debug(a.logger).Log("msg", "flattened metadata", "process_id", process.ID, "flattened_keys", len(dataMap))
+
+		err = a.db(ctx).Transaction(func(tx *gorm.DB) error {
+			return writeMetadata(tx, tenantID, process.ID, dataMap, model.MetadataSourceRegister, process.StartTime)
+		})
+		if err != nil {
+			level.Error(a.logger).Log("msg", "failed to create metadata", "process_id", process.ID, "error", err)
+			return nil, err
 		}
 	}
 
 	// Final update
 	level.Debug(a.logger).Log("msg", "updating process", "process_id", process.ID,
 		"uuid_length", len(process.ID.String()))
-	err = a.db(req.Context()).Model(&model.Process{ID: process.ID}).Updates(process).Error
+	err = a.db(ctx).Model(&model.Process{ID: process.ID}).Updates(process).Error
 	if err != nil {
 		level.Error(a.logger).Log("msg", "failed to update process", "process_id", process.ID, "error", err)
+		return nil, err
 	}
 
-	return process, err
+	return process, nil
+}
+
+// decodeJSONWithNumbers decodes JSON keeping numbers as json.Number, so that
+// metadata can be stored without losing precision.
+func decodeJSONWithNumbers(body []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	return decoder.Decode(v)
 }
 
 // Helper function to get map keys for logging
@@ -178,7 +203,33 @@ func keys(m map[string]interface{}) []string {
 	return keys
 }
 
-// registerNewProcess registers a new Process and returns a UUID.
+// Metadata views of getProcess.
+const (
+	// The metadata re-nested into the original JSON document.
+	metadataViewNested = "nested"
+	// The flattened metadata as stored.
+	metadataViewFlat = "flat"
+)
+
+// processResponse is a process with its metadata as a JSON document.
+type processResponse struct {
+	model.Process
+	Metadata map[string]interface{} `json:"metadata"`
+	// MetricSummaries are only set with the metric_summaries query
+	// parameter.
+	MetricSummaries []MetricSummary `json:"metric_summaries,omitempty"`
+}
+
+// flatProcessResponse is a process with its flattened metadata.
+type flatProcessResponse struct {
+	model.Process
+	MetricSummaries []MetricSummary `json:"metric_summaries,omitempty"`
+}
+
+// getProcess returns a process by ID.
+// The metadata is returned as a JSON document, or as the flattened key-value
+// pairs it is stored as when the metadata=flat query parameter is set. With
+// metric_summaries=true the summaries of its metrics are included.
 func (a *App) getProcess(tenantID string, req *http.Request) (interface{}, error) {
 	processID := namedParam(req, "id")
 	parsed, err := uuid.Parse(processID)
@@ -186,6 +237,27 @@ func (a *App) getProcess(tenantID string, req *http.Request) (interface{}, error
 		return nil, middleware.ErrBadRequest(err)
 	}
 
+	view := req.URL.Query().Get("metadata")
+	if view == "" {
+		view = metadataViewNested
+	}
+	if view != metadataViewNested && view != metadataViewFlat {
+		return nil, middleware.ErrBadRequest(fmt.Errorf("metadata must be %q or %q", metadataViewNested, metadataViewFlat))
+	}
+	withSummaries, err := wantsMetricSummaries(req.URL.Query())
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	// as_of returns the metadata as it was at the given time.
+	var asOf time.Time
+	if v := req.URL.Query().Get("as_of"); v != "" {
+		asOf, err = time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, middleware.ErrBadRequest(fmt.Errorf("invalid as_of: %w", err))
+		}
+	}
+
 	process := model.Process{}
 	err = a.db(req.Context()).
 		Where(&model.Process{
@@ -198,13 +270,41 @@ func (a *App) getProcess(tenantID string, req *http.Request) (interface{}, error
 
 	level.Info(a.logger).Log("msg", "found process", "tenantID", tenantID, "process_id", processID)
 
-	err = a.db(req.Context()).
-		Where(&model.MetadataKV{
-			ProcessID: parsed,
-			TenantID:  tenantID,
-		}).Find(&process.Metadata).Error
+	if asOf.IsZero() {
+		err = a.db(req.Context()).
+			Where(&model.MetadataKV{
+				ProcessID: parsed,
+				TenantID:  tenantID,
+			}).Find(&process.Metadata).Error
+		if err != nil {
+			return nil, fmt.Errorf("error finding metadata: %w", err)
+		}
+	} else {
+		process.Metadata, err = a.metadataAsOf(req.Context(), tenantID, parsed, asOf)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return process, err
+	var summaries []MetricSummary
+	if withSummaries {
+		byProcess, err := metricSummariesByProcess(req.Context(), a.db(req.Context()), tenantID, []uuid.UUID{parsed})
+		if err != nil {
+			return nil, err
+		}
+		summaries = byProcess[parsed]
+	}
+
+	if view == metadataViewFlat {
+		return flatProcessResponse{Process: process, MetricSummaries: summaries}, nil
+	}
+
+	metadata, err := model.NestMetadata(process.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	process.Metadata = nil
+	return processResponse{Process: process, Metadata: metadata, MetricSummaries: summaries}, nil
 }
 
 // deleteProcess deletes a process by ID.
@@ -229,15 +329,131 @@ func (a *App) deleteProcess(tenantID string, req *http.Request) (interface{}, er
 	return nil, err
 }
 
-// listProcess returns a list of all processes.
-// It limits the number of processes returned to listProcessLimit.
+// processCursor is the position of the last process on a page. It is
+// encoded as opaque base64 JSON so that clients do not depend on its shape.
+type processCursor struct {
+	StartTime time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+}
+
+func encodeProcessCursor(p model.Process) string {
+	b, _ := json.Marshal(processCursor{StartTime: p.StartTime, ID: p.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeProcessCursor(s string) (processCursor, error) {
+	var c processCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return c, nil
+}
+
+// listProcessQuery applies the filters and cursor from the request's query
+// string to q. It returns the page size requested by the client.
+func listProcessQuery(q *gorm.DB, tenantID string, query url.Values) (*gorm.DB, int, error) {
+	limit := listProcessLimit
+	if l := query.Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxListProcessLimit {
+			return nil, 0, fmt.Errorf("limit must be between 1 and %d", maxListProcessLimit)
+		}
+	}
+
+	if project := query.Get("project"); project != "" {
+		q = q.Where("project = ?", project)
+	}
+	if statuses := query["status"]; len(statuses) > 0 {
+		for i, status := range statuses {
+			normalized, err := model.NormalizeProcessStatus(status)
+			if err != nil {
+				return nil, 0, err
+			}
+			statuses[i] = normalized
+		}
+		if slices.Contains(statuses, model.ProcessStatusRunning) {
+			// Processes created before statuses were tracked are running.
+			statuses = append(statuses, "")
+		}
+		q = q.Where("status IN ?", statuses)
+	}
+	if groupID := query.Get("group_id"); groupID != "" {
+		parsed, err := uuid.Parse(groupID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid group_id: %w", err)
+		}
+		q = q.Where("group_id = ?", parsed)
+	}
+	if after := query.Get("started_after"); after != "" {
+		t, err := time.Parse(time.RFC3339Nano, after)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid started_after: %w", err)
+		}
+		// Start times are stored in local time, compare them in the same zone.
+		q = q.Where("start_time > ?", t.Local())
+	}
+	if before := query.Get("started_before"); before != "" {
+		t, err := time.Parse(time.RFC3339Nano, before)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid started_before: %w", err)
+		}
+		q = q.Where("start_time < ?", t.Local())
+	}
+	if expr := query.Get("filter"); expr != "" {
+		parsed, err := filter.Parse(expr)
+		if err != nil {
+			return nil, 0, err
+		}
+		cond, args, err := filter.Compile(parsed, tenantID)
+		if err != nil {
+			return nil, 0, err
+		}
+		q = q.Where(cond, args...)
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		c, err := decodeProcessCursor(cursor)
+		if err != nil {
+			return nil, 0, err
+		}
+		// Like the start time filters, compare in the zone start times are
+		// stored in: SQLite compares them as text.
+		startTime := c.StartTime.Local()
+		q = q.Where("start_time < ? OR (start_time = ? AND id < ?)", startTime, startTime, c.ID)
+	}
+
+	return q, limit, nil
+}
+
+// listProcess returns a page of processes, newest first.
+// Processes can be filtered by project, status, group, start time and a
+// metadata filter expression (see package filter). The page size defaults to
+// listProcessLimit and the response carries a cursor to the next page if
+// there is one. With metric_summaries=true the summaries of the metrics of
+// each process are included.
 func (a *App) listProcess(tenantID string, req *http.Request) (interface{}, error) {
-	var processes []model.Process
+	withSummaries, err := wantsMetricSummaries(req.URL.Query())
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+	q, limit, err := listProcessQuery(
+		a.db(req.Context()).Where("tenant_id = ?", tenantID),
+		tenantID,
+		req.URL.Query(),
+	)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
 
-	result := a.db(req.Context()).
-		Where("tenant_id = ?", tenantID).
-		Order("start_time DESC").
-		Limit(listProcessLimit).
+	// Fetch one extra process to find out if there is a next page.
+	processes := make([]model.Process, 0, limit+1)
+	result := q.
+		Order("start_time DESC, id DESC").
+		Limit(limit + 1).
 		Find(&processes)
 
 	if result.Error != nil {
@@ -247,11 +463,44 @@ func (a *App) listProcess(tenantID string, req *http.Request) (interface{}, erro
 		return nil, result.Error
 	}
 
+	page := middleware.Page{Items: processes}
+	if len(processes) > limit {
+		processes = processes[:limit]
+		page.Items = processes
+		page.NextCursor = encodeProcessCursor(processes[limit-1])
+	}
+
+	if withSummaries {
+		processIDs := make([]uuid.UUID, len(processes))
+		for i, p := range processes {
+			processIDs[i] = p.ID
+		}
+		byProcess, err := metricSummariesByProcess(req.Context(), a.db(req.Context()), tenantID, processIDs)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]flatProcessResponse, len(processes))
+		for i, p := range processes {
+			items[i] = flatProcessResponse{Process: p, MetricSummaries: byProcess[p.ID]}
+		}
+		page.Items = items
+	}
+
 	level.Info(a.logger).Log("msg", "found processes", "tenantID", tenantID, "len_processes", len(processes))
-	return processes, nil
+	return page, nil
 }
 
-// updateProcessMetadata updates the metadata of a process.
+// Content types accepted by updateProcessMetadata besides application/json.
+const (
+	contentTypeMergePatch = "application/merge-patch+json"
+	contentTypeJSONPatch  = "application/json-patch+json"
+)
+
+// updateProcessMetadata updates the metadata of a process. The body is
+// either a JSON object whose user_metadata is merged into the metadata as an
+// RFC 7386 merge patch, a bare merge patch, or an RFC 6902 JSON patch,
+// depending on its content type. Null members of a merge patch delete the
+// key and everything nested under it.
 func (a *App) updateProcessMetadata(tenantID string, req *http.Request) (interface{}, error) {
 	processID := namedParam(req, "id")
 	parsed, err := uuid.Parse(processID)
@@ -265,73 +514,226 @@ func (a *App) updateProcessMetadata(tenantID string, req *http.Request) (interfa
 		return nil, middleware.ErrBadRequest(err)
 	}
 	defer req.Body.Close()
-	var data = map[string]interface{}{}
-	err = json.Unmarshal(body, &data)
-	if err != nil {
-		return nil, middleware.ErrBadRequest(err)
+
+	source := model.MetadataSourceUpdate
+	if v := req.URL.Query().Get("source"); v != "" {
+		source = v
 	}
 
-	// Only look for metadata in the request body.
-	for key, value := range data {
-		switch key {
-		case "user_metadata":
-			metadata := value.(map[string]interface{})
-			// Flatten JSON body into key-value pairs and store in Metadata table.
-			dataMap, err := flatten.Flatten(metadata, "", flatten.DotStyle)
+	var patch func(doc interface{}) (interface{}, error)
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch contentType {
+	case contentTypeMergePatch:
+		var mergePatch interface{}
+		err = decodeJSONWithNumbers(body, &mergePatch)
+		if err != nil {
+			return nil, middleware.ErrBadRequest(err)
+		}
+		patch = func(doc interface{}) (interface{}, error) {
+			return jsonpatch.MergePatch(doc, mergePatch), nil
+		}
+
+	case contentTypeJSONPatch:
+		var ops []jsonpatch.Operation
+		err = json.Unmarshal(body, &ops)
+		if err != nil {
+			return nil, middleware.ErrBadRequest(err)
+		}
+		patch = func(doc interface{}) (interface{}, error) {
+			doc, err := jsonpatch.Apply(doc, ops)
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				return nil, middleware.ErrConflict(err)
+			}
 			if err != nil {
-				return nil, fmt.Errorf("error flattening metadata: %w", err)
+				return nil, middleware.ErrBadRequest(err)
 			}
+			return doc, nil
+		}
+
+	default:
+		var data = map[string]interface{}{}
+		err = decodeJSONWithNumbers(body, &data)
+		if err != nil {
+			return nil, middleware.ErrBadRequest(err)
+		}
 
-			// Check if these keys already exist in the Metadata table.
-			for mk, mv := range dataMap {
-				var metadata model.MetadataKV
-				err = a.db(req.Context()).
-					Where(&model.MetadataKV{
-						TenantID:  tenantID,
-						Key:       mk,
-						ProcessID: parsed,
-					}).First(&metadata).Error
-				if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
-					// If the key does not exist, create a new entry.
-					valueType, valueBytes := model.MarshalMetadataValue(mv)
-					err = a.db(req.Context()).
-						Model(&model.MetadataKV{}).
-						Create(&model.MetadataKV{
-							TenantID:  tenantID,
-							Key:       mk,
-							Value:     valueBytes,
-							Type:      valueType,
-							ProcessID: parsed,
-						}).Error
-					if err != nil {
-						return nil, fmt.Errorf("error creating metadata: %w", err)
-					}
-				} else {
-					// If the key exists, update the value.
-					valueType, valueBytes := model.MarshalMetadataValue(mv)
-					err = a.db(req.Context()).
-						Model(&model.MetadataKV{}).
-						Where(&model.MetadataKV{
-							TenantID:  tenantID,
-							Key:       mk,
-							ProcessID: parsed,
-						}).Update("value", valueBytes).
-						Update("type", valueType).Error
-					if err != nil {
-						return nil, fmt.Errorf("error updating metadata: %w", err)
-					}
+		// Only look for metadata in the request body.
+		for key, value := range data {
+			switch key {
+			case "user_metadata":
+				mergePatch, ok := value.(map[string]interface{})
+				if !ok {
+					return nil, middleware.ErrBadRequest(fmt.Errorf("user_metadata must be an object"))
+				}
+				patch = func(doc interface{}) (interface{}, error) {
+					return jsonpatch.MergePatch(doc, mergePatch), nil
 				}
+			case "source":
+				source, _ = value.(string)
+				if source == "" {
+					return nil, middleware.ErrBadRequest(fmt.Errorf("source must be a non-empty string"))
+				}
+			default:
+				level.Error(a.logger).Log("msg", "unknown key in request body", "key", key)
 			}
-			continue
-		default:
-			level.Error(a.logger).Log("msg", "unknown key in request body", "key", key)
 		}
 	}
 
+	err = a.patchProcessMetadata(req.Context(), tenantID, parsed, patch, source)
+	if err != nil {
+		return nil, err
+	}
+
 	level.Info(a.logger).Log("msg", "updated metadata", "tenantID", tenantID, "process_id", processID)
 
 	// Return the process ID.
-	return model.Process{ID: parsed}, err
+	return model.Process{ID: parsed}, nil
+}
+
+// patchProcessMetadata applies a patch, if any, to the metadata of a process
+// of the tenant.
+func (a *App) patchProcessMetadata(ctx context.Context, tenantID string, processID uuid.UUID, patch func(doc interface{}) (interface{}, error), source string) error {
+	if _, err := a.findProcess(ctx, tenantID, processID); err != nil {
+		return err
+	}
+
+	if patch == nil {
+		return nil
+	}
+	now := time.Now()
+	return a.db(ctx).Transaction(func(tx *gorm.DB) error {
+		return patchMetadata(tx, tenantID, processID, patch, source, now)
+	})
+}
+
+type updateProcessStateRequest struct {
+	State   string `json:"state"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// updateProcessState moves a process to a new state. Only transitions allowed
+// by the process lifecycle are accepted; terminal states also set EndTime.
+func (a *App) updateProcessState(tenantID string, req *http.Request) (interface{}, error) {
+	processID := namedParam(req, "id")
+	parsed, err := uuid.Parse(processID)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	// Read and parse request body.
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+	defer req.Body.Close()
+	var data = updateProcessStateRequest{}
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	process, err := a.transitionProcess(req.Context(), tenantID, parsed, data)
+	if err != nil {
+		return nil, err
+	}
+
+	level.Info(a.logger).Log("msg", "updated process state", "tenantID", tenantID, "process_id", processID, "state", process.Status)
+	return process, nil
+}
+
+// transitionProcess moves a process of the tenant to the requested state.
+func (a *App) transitionProcess(ctx context.Context, tenantID string, processID uuid.UUID, data updateProcessStateRequest) (model.Process, error) {
+	if _, err := model.NormalizeProcessStatus(data.State); err != nil {
+		return model.Process{}, middleware.ErrBadRequest(err)
+	}
+
+	process := model.Process{}
+	err := a.db(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where(&model.Process{
+			TenantID: tenantID,
+			ID:       processID,
+		}).First(&process).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return middleware.ErrNotFound(fmt.Errorf("process not found"))
+			}
+			return fmt.Errorf("error finding process: %w", err)
+		}
+
+		previous := process.Status
+		err = process.Transition(data.State, time.Now())
+		if err != nil {
+			var invalid model.ErrInvalidProcessTransition
+			if errors.As(err, &invalid) {
+				return middleware.ErrConflict(err)
+			}
+			return middleware.ErrBadRequest(err)
+		}
+		if process.Status == previous {
+			return nil
+		}
+		process.ExitReason = data.Reason
+		process.ExitMessage = data.Message
+
+		return tx.Model(&model.Process{}).
+			Where("tenant_id = ? AND id = ?", tenantID, processID).
+			Updates(map[string]interface{}{
+				"status":       process.Status,
+				"end_time":     process.EndTime,
+				"exit_reason":  process.ExitReason,
+				"exit_message": process.ExitMessage,
+			}).Error
+	})
+	if err != nil {
+		return model.Process{}, err
+	}
+	return process, nil
+}
+
+// processHeartbeat records that a running process is still alive.
+func (a *App) processHeartbeat(tenantID string, req *http.Request) (interface{}, error) {
+	processID := namedParam(req, "id")
+	parsed, err := uuid.Parse(processID)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	process := model.Process{}
+	err = a.db(req.Context()).
+		Where(&model.Process{
+			TenantID: tenantID,
+			ID:       parsed,
+		}).First(&process).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, middleware.ErrNotFound(fmt.Errorf("process not found"))
+		}
+		return nil, fmt.Errorf("error finding process: %w", err)
+	}
+
+	if model.IsTerminalProcessStatus(process.Status) {
+		return nil, middleware.ErrConflict(fmt.Errorf("process is %s", process.Status))
+	}
+
+	err = a.touchProcess(req.Context(), tenantID, parsed, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	level.Debug(a.logger).Log("msg", "received heartbeat", "tenantID", tenantID, "process_id", processID)
+	return nil, nil
+}
+
+// touchProcess records that a process was alive at the given time.
+func (a *App) touchProcess(ctx context.Context, tenantID string, processID uuid.UUID, now time.Time) error {
+	err := a.db(ctx).Model(&model.Process{}).
+		Where("tenant_id = ? AND id = ?", tenantID, processID).
+		Update("last_heartbeat_at", now).Error
+	if err != nil {
+		return fmt.Errorf("error updating heartbeat: %w", err)
+	}
+	return nil
 }
 
 type registerNewGroupRequest struct {
@@ -355,21 +757,31 @@ func (a *App) registerNewGroup(tenantID string, req *http.Request) (interface{},
 
 	// Create unique group ID.
 	groupId := uuid.New()
-	err = a.db(req.Context()).Create(&model.Group{
-		TenantID: tenantID,
-		ID:       groupId,
-		Name:     data.Name,
-	}).Error
-	if err != nil {
-		return nil, fmt.Errorf("error creating group: %w", err)
-	}
+	err = a.db(req.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&model.Group{
+			TenantID: tenantID,
+			ID:       groupId,
+			Name:     data.Name,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("error creating group: %w", err)
+		}
 
-	// Add processes to the group.
-	err = a.db(req.Context()).Model(&model.Process{}).
-		Where("id IN ?", data.ProcessIDs).
-		Update("group_id", groupId).Error
+		// Add processes to the group. Processes of other tenants are
+		// reported as not found and left untouched.
+		results, err := addProcessesToGroup(tx, tenantID, groupId, data.ProcessIDs)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			if r.Result == GroupMemberNotFound {
+				level.Warn(a.logger).Log("msg", "process not found for new group", "tenantID", tenantID, "group_id", groupId, "process_id", r.ProcessID)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error adding processes to group: %w", err)
+		return nil, err
 	}
 
 	level.Info(a.logger).Log("msg", "registered new group", "tenantID", tenantID, "group_id", groupId)
@@ -387,7 +799,7 @@ func (a *App) getGroup(tenantID string, req *http.Request) (interface{}, error)
 
 	group := model.Group{}
 	err = a.db(req.Context()).
-		Preload("Processes").
+		Preload("Processes", "tenant_id = ?", tenantID).
 		Where(&model.Group{
 			TenantID: tenantID,
 			ID:       parsed,
@@ -405,7 +817,7 @@ func (a *App) getGroup(tenantID string, req *http.Request) (interface{}, error)
 func (a *App) getGroups(tenantID string, req *http.Request) (interface{}, error) {
 	groups := []model.Group{}
 	err := a.db(req.Context()).
-		Preload("Processes").
+		Preload("Processes", "tenant_id = ?", tenantID).
 		Where(&model.Group{
 			TenantID: tenantID,
 		}).Find(&groups).Limit(limitGroupLimit).Error
@@ -417,6 +829,81 @@ func (a *App) getGroups(tenantID string, req *http.Request) (interface{}, error)
 	return groups, err
 }
 
+type updateGroupRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	// Metadata is merged into the group's metadata as an RFC 7386 merge
+	// patch.
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// updateGroup edits the name, description and metadata of a group and
+// returns the updated group. Fields missing from the request are left as
+// they are.
+func (a *App) updateGroup(tenantID string, req *http.Request) (interface{}, error) {
+	groupId := namedParam(req, "id")
+	parsed, err := uuid.Parse(groupId)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	// Read and parse request body.
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+	defer req.Body.Close()
+	var data updateGroupRequest
+	err = decodeJSONWithNumbers(body, &data)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	group := model.Group{}
+	err = a.db(req.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Where(&model.Group{
+				TenantID: tenantID,
+				ID:       parsed,
+			}).First(&group).Error
+		if err != nil {
+			return middleware.ErrNotFound(err)
+		}
+
+		if data.Name != nil {
+			group.Name = *data.Name
+		}
+		if data.Description != nil {
+			group.Description = *data.Description
+		}
+		if data.Metadata != nil {
+			err = group.MergeMetadata(data.Metadata)
+			var tooLarge model.ErrGroupMetadataTooLarge
+			if errors.As(err, &tooLarge) {
+				return middleware.ErrBadRequest(err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Model(&group).
+			Select("name", "description", "metadata").
+			Updates(&group).Error
+		if err != nil {
+			return fmt.Errorf("error updating group: %w", err)
+		}
+
+		return tx.Preload("Processes", "tenant_id = ?", tenantID).First(&group, "id = ?", parsed).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	level.Info(a.logger).Log("msg", "updated group", "tenantID", tenantID, "group_id", groupId)
+	return group, nil
+}
+
 // deleteGroup deletes a group by ID.
 func (a *App) deleteGroup(tenantID string, req *http.Request) (interface{}, error) {
 	groupId := namedParam(req, "id")
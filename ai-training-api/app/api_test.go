@@ -2,11 +2,18 @@ package api
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -52,6 +59,11 @@ type createProcessResponse struct {
 }
 
 type getProcessResponse struct {
+	middleware.ResponseWrapper
+	Data processResponse `json:"data"`
+}
+
+type getFlatProcessResponse struct {
 	middleware.ResponseWrapper
 	Data model.Process `json:"data"`
 }
@@ -92,11 +104,17 @@ func NewTestApp(t *testing.T, logger log.Logger) *App {
 	testApp, err := New(
 		listenAddress,
 		listenPort,
+		0, // grpcListenPort
 		filepath.Join(t.TempDir(), "test.db"),
 		db.SQLite,
 		"0", // constTenant
 		"",  // lokiAddress
 		"",  // lokiTenant
+		time.Hour,
+		map[string]time.Duration{"short": time.Minute},
+		1<<20, // maxMetricsPayloadBytes
+		RemoteWriteConfig{ProcessLabel: "process_id", MetricLabel: "__name__", StepLabel: "step", StepName: "step"},
+		OTLPConfig{ProcessAttribute: "process_id", StepAttribute: "step"},
 		&promlog.Config{Level: logLevel, Format: logFormat},
 	)
 	require.NoError(t, err)
@@ -122,13 +140,19 @@ func TestAppCreatesNewProcess(t *testing.T) {
 
 	// Verify the process was created.
 	getProcessEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/" + cpr.Data.ID.String()
-	resp, err = httpC.Get(getProcessEndpoint)
+	resp, err = httpC.Get(getProcessEndpoint + "?metadata=flat")
 	require.NoError(t, err)
-	gpr := read[getProcessResponse](t, resp)
+	gpr := read[getFlatProcessResponse](t, resp)
 	assert.Equal(t, cpr.Data.ID, gpr.Data.ID)
 	assert.Len(t, gpr.Data.Metadata, 2)
 	assert.Contains(t, gpr.Data.Metadata, model.MetadataKV{TenantID: "0", Key: "key1", Type: "string", Value: []byte("value1"), ProcessID: cpr.Data.ID})
 	assert.Contains(t, gpr.Data.Metadata, model.MetadataKV{TenantID: "0", Key: "key2", Type: "int", Value: []byte("2"), ProcessID: cpr.Data.ID})
+
+	// By default the metadata is returned as the original document.
+	resp, err = httpC.Get(getProcessEndpoint)
+	require.NoError(t, err)
+	ngpr := read[getProcessResponse](t, resp)
+	assert.Equal(t, map[string]interface{}{"key1": "value1", "key2": 2.0}, ngpr.Data.Metadata)
 }
 
 func TestAppCreatesNewProcessAndGroup(t *testing.T) {
@@ -192,14 +216,14 @@ func TestAppCreatesAndUpdatesMetadata(t *testing.T) {
 	defer resp.Body.Close()
 
 	// Verify the metadata was updated.
-	resp, err = httpC.Get(getProcessEndpoint)
+	resp, err = httpC.Get(getProcessEndpoint + "?metadata=flat")
 	require.NoError(t, err)
-	gpr = read[getProcessResponse](t, resp)
-	assert.Equal(t, cpr.Data.ID, gpr.Data.ID)
-	assert.Len(t, gpr.Data.Metadata, 3)
-	assert.Contains(t, gpr.Data.Metadata, model.MetadataKV{TenantID: "0", Key: "key1", Type: "string", Value: []byte("completely_different_value"), ProcessID: cpr.Data.ID})
-	assert.Contains(t, gpr.Data.Metadata, model.MetadataKV{TenantID: "0", Key: "key2", Type: "int", Value: []byte("2"), ProcessID: cpr.Data.ID})
-	assert.Contains(t, gpr.Data.Metadata, model.MetadataKV{TenantID: "0", Key: "key3", Type: "string", Value: []byte("value3"), ProcessID: cpr.Data.ID})
+	fgpr := read[getFlatProcessResponse](t, resp)
+	assert.Equal(t, cpr.Data.ID, fgpr.Data.ID)
+	assert.Len(t, fgpr.Data.Metadata, 3)
+	assert.Contains(t, fgpr.Data.Metadata, model.MetadataKV{TenantID: "0", Key: "key1", Type: "string", Value: []byte("completely_different_value"), ProcessID: cpr.Data.ID})
+	assert.Contains(t, fgpr.Data.Metadata, model.MetadataKV{TenantID: "0", Key: "key2", Type: "int", Value: []byte("2"), ProcessID: cpr.Data.ID})
+	assert.Contains(t, fgpr.Data.Metadata, model.MetadataKV{TenantID: "0", Key: "key3", Type: "string", Value: []byte("value3"), ProcessID: cpr.Data.ID})
 }
 
 func TestAppCreatesAndDeletesProcessAndGroup(t *testing.T) {
@@ -248,7 +272,7 @@ func TestAppCreatesAndDeletesProcessAndGroup(t *testing.T) {
 	require.Equal(t, http.StatusNotFound, resp.StatusCode)
 }
 
-func TestAppSetsCorrectEndTime(t *testing.T) {
+func TestAppDoesNotWriteOnRead(t *testing.T) {
 	logger := log.NewNopLogger()
 	testApp := NewTestApp(t, logger)
 	require.NotNil(t, testApp)
@@ -259,19 +283,119 @@ func TestAppSetsCorrectEndTime(t *testing.T) {
 	process := model.Process{
 		ID:        uuid.New(),
 		TenantID:  "0",
+		Status:    model.ProcessStatusRunning,
 		StartTime: startTime,
 	}
 	db := testApp.db(context.Background())
 	require.NoError(t, db.Create(&process).Error)
 
-	// Query the process and verify that the end time was set correctly.
+	// Reading the process must not make up an end time.
 	httpC := newHTTPClient(t.Name())
 	getProcessEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/" + process.ID.String()
 	resp, err := httpC.Get(getProcessEndpoint)
 	require.NoError(t, err)
 	gpr := read[getProcessResponse](t, resp)
 	assert.Equal(t, process.ID, gpr.Data.ID)
-	assert.Equal(t, gpr.Data.EndTime.Time, gpr.Data.StartTime.Add(time.Hour))
+	assert.False(t, gpr.Data.EndTime.Valid)
+	assert.Equal(t, model.ProcessStatusRunning, gpr.Data.Status)
+}
+
+func TestAppRecordsHeartbeats(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	registerProcessEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/new"
+	resp, err := httpC.Post(registerProcessEndpoint, "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	cpr := read[createProcessResponse](t, resp)
+	assert.False(t, cpr.Data.LastHeartbeatAt.Valid)
+
+	heartbeatEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/" + cpr.Data.ID.String() + "/heartbeat"
+	resp, err = httpC.Post(heartbeatEndpoint, "application/json", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	getProcessEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/" + cpr.Data.ID.String()
+	resp, err = httpC.Get(getProcessEndpoint)
+	require.NoError(t, err)
+	gpr := read[getProcessResponse](t, resp)
+	assert.True(t, gpr.Data.LastHeartbeatAt.Valid)
+
+	// Finished processes no longer accept heartbeats.
+	stateEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/" + cpr.Data.ID.String() + "/state"
+	resp, err = httpC.Post(stateEndpoint, "application/json", bytes.NewBufferString(`{"state": "succeeded"}`))
+	require.NoError(t, err)
+	read[getProcessResponse](t, resp)
+
+	resp, err = httpC.Post(heartbeatEndpoint, "application/json", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestAppReapsStaleProcesses(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	now := time.Now()
+	heartbeat := func(d time.Duration) sql.NullTime {
+		return sql.NullTime{Time: now.Add(-d), Valid: true}
+	}
+	processes := map[string]model.Process{
+		"stale": {
+			ID: uuid.New(), TenantID: "0", Status: model.ProcessStatusRunning,
+			StartTime: now.Add(-3 * time.Hour), LastHeartbeatAt: heartbeat(2 * time.Hour),
+		},
+		"never_heartbeat": {
+			ID: uuid.New(), TenantID: "0", Status: model.ProcessStatusRunning,
+			StartTime: now.Add(-2 * time.Hour),
+		},
+		"long_running": {
+			ID: uuid.New(), TenantID: "0", Status: model.ProcessStatusRunning,
+			StartTime: now.Add(-48 * time.Hour), LastHeartbeatAt: heartbeat(time.Minute),
+		},
+		"finished": {
+			ID: uuid.New(), TenantID: "0", Status: model.ProcessStatusSucceeded,
+			StartTime: now.Add(-3 * time.Hour), EndTime: heartbeat(2 * time.Hour),
+		},
+		"short_tenant": {
+			ID: uuid.New(), TenantID: "short", Status: model.ProcessStatusRunning,
+			StartTime: now.Add(-time.Hour), LastHeartbeatAt: heartbeat(5 * time.Minute),
+		},
+	}
+	db := testApp.db(context.Background())
+	for _, p := range processes {
+		require.NoError(t, db.Create(&p).Error)
+	}
+
+	reaped, err := testApp.reapStaleProcesses(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, reaped)
+
+	expected := map[string]string{
+		"stale":           model.ProcessStatusCrashed,
+		"never_heartbeat": model.ProcessStatusCrashed,
+		"long_running":    model.ProcessStatusRunning,
+		"finished":        model.ProcessStatusSucceeded,
+		"short_tenant":    model.ProcessStatusCrashed,
+	}
+	for name, status := range expected {
+		var p model.Process
+		require.NoError(t, testApp.db(context.Background()).First(&p, "id = ?", processes[name].ID).Error)
+		assert.Equal(t, status, p.Status, name)
+		if status == model.ProcessStatusCrashed {
+			original := processes[name]
+			assert.Equal(t, reaperExitReason, p.ExitReason, name)
+			assert.WithinDuration(t, original.LastSeen(), p.EndTime.Time, time.Second, name)
+		}
+	}
 }
 
 // This tests for the case where the same group name is added to two process
@@ -350,3 +474,1261 @@ func TestAppCreatesGroupWithMultipleProcesses(t *testing.T) {
 	assert.Equal(t, ggsr.Data[0].Processes[0].ID, cpr.Data.ID)
 	assert.Equal(t, ggsr.Data[0].Processes[1].ID, cpr2.Data.ID)
 }
+
+func TestAppUpdatesGroup(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessWithGroupNameJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+	require.NotNil(t, cpr.Data.GroupID)
+	updateGroupEndpoint := baseURL + "/group/" + cpr.Data.GroupID.String() + "/update"
+
+	resp, err = httpC.Post(updateGroupEndpoint, "application/json", bytes.NewBufferString(
+		`{"description": "lr sweep", "metadata": {"sweep": {"lr": [0.1, 0.01], "seed": 1}, "repo": "github.com/org/repo"}}`))
+	require.NoError(t, err)
+	ggr := read[getGroupResponse](t, resp)
+	assert.Equal(t, "group1", ggr.Data.Name)
+	assert.Equal(t, "lr sweep", ggr.Data.Description)
+	assert.JSONEq(t, `{"sweep": {"lr": [0.1, 0.01], "seed": 1}, "repo": "github.com/org/repo"}`, string(ggr.Data.Metadata))
+	require.Len(t, ggr.Data.Processes, 1)
+	assert.Equal(t, cpr.Data.ID, ggr.Data.Processes[0].ID)
+
+	// Metadata is merged, null removes keys.
+	resp, err = httpC.Post(updateGroupEndpoint, "application/json", bytes.NewBufferString(
+		`{"name": "sweep-1", "metadata": {"sweep": {"seed": null}, "checkpoint": "s3://bucket/ckpt"}}`))
+	require.NoError(t, err)
+	ggr = read[getGroupResponse](t, resp)
+	assert.Equal(t, "sweep-1", ggr.Data.Name)
+	assert.Equal(t, "lr sweep", ggr.Data.Description)
+	assert.JSONEq(t, `{"sweep": {"lr": [0.1, 0.01]}, "repo": "github.com/org/repo", "checkpoint": "s3://bucket/ckpt"}`, string(ggr.Data.Metadata))
+
+	resp, err = httpC.Get(baseURL + "/group/" + cpr.Data.GroupID.String())
+	require.NoError(t, err)
+	assert.Equal(t, ggr.Data.Metadata, read[getGroupResponse](t, resp).Data.Metadata)
+
+	// The metadata is capped.
+	var sb strings.Builder
+	sb.WriteString(`{"metadata": {`)
+	for i := 0; i < model.MaxGroupMetadataKeys; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `"k%d": %d`, i, i)
+	}
+	sb.WriteString(`}}`)
+	resp, err = httpC.Post(updateGroupEndpoint, "application/json", bytes.NewBufferString(sb.String()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = httpC.Post(baseURL+"/group/"+uuid.NewString()+"/update", "application/json", bytes.NewBufferString(`{"name": "x"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+type groupMembersResponseWrapper struct {
+	middleware.ResponseWrapper
+	Data groupMembersResponse `json:"data"`
+}
+
+func earliestStart(processes []model.Process) time.Time {
+	start := processes[0].StartTime
+	for _, p := range processes {
+		if p.StartTime.Before(start) {
+			start = p.StartTime
+		}
+	}
+	return start
+}
+
+func processStart(t *testing.T, testApp *App, id uuid.UUID) time.Time {
+	var p model.Process
+	require.NoError(t, testApp.db(context.Background()).First(&p, "id = ?", id).Error)
+	return p.StartTime
+}
+
+func TestAppManagesGroupMembers(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+
+	var processIDs []uuid.UUID
+	for i := 0; i < 3; i++ {
+		resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+		require.NoError(t, err)
+		processIDs = append(processIDs, read[createProcessResponse](t, resp).Data.ID)
+	}
+
+	// A process of another tenant.
+	otherID := uuid.New()
+	require.NoError(t, testApp.db(context.Background()).Create(&model.Process{
+		ID:        otherID,
+		TenantID:  "other",
+		Status:    model.ProcessStatusRunning,
+		StartTime: time.Now(),
+	}).Error)
+
+	// Creating a group does not pull in processes of other tenants.
+	resp, err := httpC.Post(baseURL+"/group/new", "application/json", bytes.NewBufferString(
+		`{"name": "a", "process_ids": ["`+processIDs[0].String()+`", "`+otherID.String()+`"]}`))
+	require.NoError(t, err)
+	groupA := read[getGroupResponse](t, resp).Data.ID
+
+	var other model.Process
+	require.NoError(t, testApp.db(context.Background()).First(&other, "id = ?", otherID).Error)
+	assert.Nil(t, other.GroupID)
+
+	resp, err = httpC.Post(baseURL+"/group/new", "application/json", bytes.NewBufferString(`{"name": "b"}`))
+	require.NoError(t, err)
+	groupB := read[getGroupResponse](t, resp).Data.ID
+
+	membersRequest := func(ids ...uuid.UUID) *bytes.Buffer {
+		b, err := json.Marshal(groupMembersRequest{ProcessIDs: ids})
+		require.NoError(t, err)
+		return bytes.NewBuffer(b)
+	}
+
+	// Move the process from group a to group b, and add another one.
+	resp, err = httpC.Post(baseURL+"/group/"+groupB.String()+"/add-members", "application/json",
+		membersRequest(processIDs[0], processIDs[1], otherID, processIDs[1]))
+	require.NoError(t, err)
+	gmr := read[groupMembersResponseWrapper](t, resp)
+	assert.Equal(t, []GroupMemberResult{
+		{ProcessID: processIDs[0], Result: GroupMemberMoved},
+		{ProcessID: processIDs[1], Result: GroupMemberMoved},
+		{ProcessID: otherID, Result: GroupMemberNotFound},
+	}, gmr.Data.Results)
+	require.Len(t, gmr.Data.Group.Processes, 2)
+	assert.True(t, gmr.Data.Group.StartTime.Equal(earliestStart(gmr.Data.Group.Processes)))
+
+	resp, err = httpC.Get(baseURL + "/group/" + groupA.String())
+	require.NoError(t, err)
+	assert.Empty(t, read[getGroupResponse](t, resp).Data.Processes)
+
+	resp, err = httpC.Post(baseURL+"/group/"+groupB.String()+"/add-members", "application/json",
+		membersRequest(processIDs[1], processIDs[2]))
+	require.NoError(t, err)
+	gmr = read[groupMembersResponseWrapper](t, resp)
+	assert.Equal(t, []GroupMemberResult{
+		{ProcessID: processIDs[1], Result: GroupMemberAlreadyMember},
+		{ProcessID: processIDs[2], Result: GroupMemberMoved},
+	}, gmr.Data.Results)
+	assert.Len(t, gmr.Data.Group.Processes, 3)
+
+	resp, err = httpC.Post(baseURL+"/group/"+groupB.String()+"/remove-members", "application/json",
+		membersRequest(processIDs[0], otherID))
+	require.NoError(t, err)
+	gmr = read[groupMembersResponseWrapper](t, resp)
+	assert.Equal(t, []GroupMemberResult{
+		{ProcessID: processIDs[0], Result: GroupMemberRemoved},
+		{ProcessID: otherID, Result: GroupMemberNotFound},
+	}, gmr.Data.Results)
+	require.Len(t, gmr.Data.Group.Processes, 2)
+	// The group now starts with its earliest remaining process.
+	assert.True(t, gmr.Data.Group.StartTime.Equal(earliestStart(gmr.Data.Group.Processes)))
+	assert.True(t, gmr.Data.Group.StartTime.After(processStart(t, testApp, processIDs[0])))
+
+	resp, err = httpC.Post(baseURL+"/group/"+groupA.String()+"/remove-members", "application/json",
+		membersRequest(processIDs[1]))
+	require.NoError(t, err)
+	gmr = read[groupMembersResponseWrapper](t, resp)
+	assert.Equal(t, []GroupMemberResult{{ProcessID: processIDs[1], Result: GroupMemberNotMember}}, gmr.Data.Results)
+	// Group a was emptied by moving its process out.
+	assert.True(t, gmr.Data.Group.StartTime.IsZero())
+	assert.False(t, gmr.Data.Group.EndTime.Valid)
+
+	// Emptying a group clears its times.
+	require.NoError(t, testApp.db(context.Background()).Model(&model.Process{}).
+		Where("id = ?", processIDs[1]).Update("end_time", time.Now()).Error)
+	resp, err = httpC.Post(baseURL+"/group/"+groupB.String()+"/remove-members", "application/json",
+		membersRequest(processIDs[2]))
+	require.NoError(t, err)
+	gmr = read[groupMembersResponseWrapper](t, resp)
+	assert.True(t, gmr.Data.Group.EndTime.Valid)
+	resp, err = httpC.Post(baseURL+"/group/"+groupB.String()+"/remove-members", "application/json",
+		membersRequest(processIDs[1]))
+	require.NoError(t, err)
+	gmr = read[groupMembersResponseWrapper](t, resp)
+	assert.Empty(t, gmr.Data.Group.Processes)
+	assert.True(t, gmr.Data.Group.StartTime.IsZero())
+	assert.False(t, gmr.Data.Group.EndTime.Valid)
+
+	// Processes of other tenants attached to a group before memberships
+	// were scoped to the tenant are neither returned nor counted.
+	require.NoError(t, testApp.db(context.Background()).Model(&model.Process{}).
+		Where("id = ?", otherID).Updates(map[string]interface{}{"group_id": groupA, "start_time": time.Now().Add(-time.Hour)}).Error)
+	resp, err = httpC.Post(baseURL+"/group/"+groupA.String()+"/add-members", "application/json",
+		membersRequest(processIDs[0]))
+	require.NoError(t, err)
+	gmr = read[groupMembersResponseWrapper](t, resp)
+	require.Len(t, gmr.Data.Group.Processes, 1)
+	assert.Equal(t, processIDs[0], gmr.Data.Group.Processes[0].ID)
+	assert.True(t, gmr.Data.Group.StartTime.Equal(processStart(t, testApp, processIDs[0])))
+	resp, err = httpC.Get(baseURL + "/group/" + groupA.String())
+	require.NoError(t, err)
+	assert.Len(t, read[getGroupResponse](t, resp).Data.Processes, 1)
+
+	resp, err = httpC.Post(baseURL+"/group/"+uuid.NewString()+"/add-members", "application/json", membersRequest(processIDs[0]))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestAppUpdatesProcessState(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	registerProcessEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/new"
+	resp, err := httpC.Post(registerProcessEndpoint, "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	cpr := read[createProcessResponse](t, resp)
+	assert.Equal(t, model.ProcessStatusRunning, cpr.Data.Status)
+
+	// Unknown states are rejected.
+	stateEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/" + cpr.Data.ID.String() + "/state"
+	resp, err = httpC.Post(stateEndpoint, "application/json", bytes.NewBufferString(`{"state": "exploded"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Move the process to a terminal state.
+	resp, err = httpC.Post(stateEndpoint, "application/json", bytes.NewBufferString(`{"state": "failed", "reason": "oom", "message": "CUDA out of memory"}`))
+	require.NoError(t, err)
+	spr := read[getProcessResponse](t, resp)
+	assert.Equal(t, model.ProcessStatusFailed, spr.Data.Status)
+	assert.Equal(t, "oom", spr.Data.ExitReason)
+	assert.Equal(t, "CUDA out of memory", spr.Data.ExitMessage)
+	assert.True(t, spr.Data.EndTime.Valid)
+
+	// Repeating the same state is a no-op.
+	resp, err = httpC.Post(stateEndpoint, "application/json", bytes.NewBufferString(`{"state": "failed"}`))
+	require.NoError(t, err)
+	spr = read[getProcessResponse](t, resp)
+	assert.Equal(t, "oom", spr.Data.ExitReason)
+
+	// Terminal states cannot be left.
+	resp, err = httpC.Post(stateEndpoint, "application/json", bytes.NewBufferString(`{"state": "succeeded"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// Verify the state was persisted.
+	getProcessEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/" + cpr.Data.ID.String()
+	resp, err = httpC.Get(getProcessEndpoint)
+	require.NoError(t, err)
+	gpr := read[getProcessResponse](t, resp)
+	assert.Equal(t, model.ProcessStatusFailed, gpr.Data.Status)
+	assert.Equal(t, "oom", gpr.Data.ExitReason)
+	assert.True(t, gpr.Data.EndTime.Valid)
+}
+
+func TestAppAcceptsLegacySuccessState(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	registerProcessEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/new"
+	resp, err := httpC.Post(registerProcessEndpoint, "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	cpr := read[createProcessResponse](t, resp)
+
+	// The Python client reports "successful" when finishing a process.
+	stateEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/" + cpr.Data.ID.String() + "/state"
+	resp, err = httpC.Post(stateEndpoint, "application/json", bytes.NewBufferString(`{"state": "successful"}`))
+	require.NoError(t, err)
+	spr := read[getProcessResponse](t, resp)
+	assert.Equal(t, model.ProcessStatusSucceeded, spr.Data.Status)
+}
+
+type listProcessesResponse struct {
+	middleware.ResponseWrapper
+	Data []model.Process `json:"data"`
+}
+
+func TestAppListsProcessesWithFiltersAndCursor(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	// Create processes one minute apart, alternating between two projects.
+	now := time.Now().Truncate(time.Second)
+	groupID := uuid.New()
+	require.NoError(t, testApp.db(context.Background()).Create(&model.Group{ID: groupID, TenantID: "0"}).Error)
+	var created []model.Process
+	for i := 0; i < 5; i++ {
+		p := model.Process{
+			ID:        uuid.New(),
+			TenantID:  "0",
+			Status:    model.ProcessStatusRunning,
+			StartTime: now.Add(time.Duration(i-5) * time.Minute),
+			Project:   []string{"a", "b"}[i%2],
+		}
+		if i == 4 {
+			p.Status = model.ProcessStatusFailed
+			p.GroupID = &groupID
+		}
+		if i == 1 {
+			// Created before statuses were tracked.
+			p.Status = ""
+		}
+		require.NoError(t, testApp.db(context.Background()).Create(&p).Error)
+		created = append(created, p)
+	}
+	// A process of another tenant is never listed.
+	require.NoError(t, testApp.db(context.Background()).Create(&model.Process{ID: uuid.New(), TenantID: "1", StartTime: now}).Error)
+
+	httpC := newHTTPClient(t.Name())
+	list := func(query string) listProcessesResponse {
+		t.Helper()
+		resp, err := httpC.Get("http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/processes?" + query)
+		require.NoError(t, err)
+		return read[listProcessesResponse](t, resp)
+	}
+	ids := func(processes []model.Process) []uuid.UUID {
+		out := make([]uuid.UUID, 0, len(processes))
+		for _, p := range processes {
+			out = append(out, p.ID)
+		}
+		return out
+	}
+
+	// Page through all processes, newest first.
+	var seen []uuid.UUID
+	lpr := list("limit=2")
+	pages := 1
+	seen = append(seen, ids(lpr.Data)...)
+	for lpr.NextCursor != "" {
+		lpr = list("limit=2&cursor=" + lpr.NextCursor)
+		seen = append(seen, ids(lpr.Data)...)
+		pages++
+	}
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []uuid.UUID{created[4].ID, created[3].ID, created[2].ID, created[1].ID, created[0].ID}, seen)
+
+	// Cursors compare start times as instants, whatever their zone.
+	cursor := encodeProcessCursor(model.Process{ID: created[3].ID, StartTime: created[3].StartTime.In(time.FixedZone("", 5*3600))})
+	assert.Equal(t, []uuid.UUID{created[2].ID, created[1].ID, created[0].ID}, ids(list("cursor="+cursor).Data))
+
+	// Filters.
+	assert.Equal(t, []uuid.UUID{created[4].ID, created[2].ID, created[0].ID}, ids(list("project=a").Data))
+	assert.Equal(t, []uuid.UUID{created[4].ID}, ids(list("status=failed").Data))
+	assert.Equal(t, []uuid.UUID{created[3].ID, created[2].ID, created[1].ID, created[0].ID}, ids(list("status=running").Data))
+	assert.Equal(t, []uuid.UUID{created[4].ID}, ids(list("group_id="+groupID.String()).Data))
+	assert.Equal(t, []uuid.UUID{created[3].ID, created[2].ID}, ids(list(url.Values{
+		"started_after":  {created[1].StartTime.UTC().Format(time.RFC3339)},
+		"started_before": {created[4].StartTime.UTC().Format(time.RFC3339)},
+	}.Encode()).Data))
+	assert.Empty(t, list("project=c").Data)
+
+	// Invalid parameters are rejected.
+	for _, query := range []string{"limit=0", "limit=abc", "status=exploded", "group_id=abc", "started_after=yesterday", "cursor=!!!"} {
+		resp, err := httpC.Get("http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/processes?" + query)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func TestAppListsProcessesMatchingMetadataFilter(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	registerProcessEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/new"
+	register := func(body string) uuid.UUID {
+		t.Helper()
+		resp, err := httpC.Post(registerProcessEndpoint, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		return read[createProcessResponse](t, resp).Data.ID
+	}
+	adam := register(`{"user_metadata": {"optimizer": "adam", "lr": 0.0001, "model": {"layers": 24}, "use_amp": true}}`)
+	adamHighLR := register(`{"user_metadata": {"optimizer": "adam", "lr": 0.01, "model": {"layers": 12}}}`)
+	sgd := register(`{"user_metadata": {"optimizer": "sgd", "lr": 0.0001, "model": {"layers": 6}}}`)
+
+	list := func(filter string) []uuid.UUID {
+		t.Helper()
+		resp, err := httpC.Get("http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/processes?" + url.Values{"filter": {filter}}.Encode())
+		require.NoError(t, err)
+		var ids []uuid.UUID
+		for _, p := range read[listProcessesResponse](t, resp).Data {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	assert.ElementsMatch(t, []uuid.UUID{adam}, list(`optimizer = "adam" AND lr < 0.001 AND model.layers >= 12`))
+	assert.ElementsMatch(t, []uuid.UUID{adam, adamHighLR}, list(`optimizer = "adam"`))
+	assert.ElementsMatch(t, []uuid.UUID{adamHighLR, sgd}, list(`model.layers <= 12`))
+	assert.ElementsMatch(t, []uuid.UUID{adam, sgd}, list(`lr = 1e-4`))
+	assert.ElementsMatch(t, []uuid.UUID{adam}, list(`use_amp = true`))
+	assert.ElementsMatch(t, []uuid.UUID{adamHighLR, sgd}, list(`NOT use_amp = true`))
+	assert.ElementsMatch(t, []uuid.UUID{sgd, adamHighLR}, list(`optimizer != "adam" OR lr > 0.001`))
+	// Strings never match numbers.
+	assert.Empty(t, list(`model.layers = "24"`))
+
+	resp, err := httpC.Get("http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/processes?" + url.Values{"filter": {`optimizer = `}}.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAppReturnsMetadataLosslessly(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	metadata := `{"seed":9223372036854775807,"tokens":123456789012345678901234567890,"lr":0.001,"warmup":1.0,"checkpoint":null,"empty":{},"layers":[],"model":{"dims":[512,{"heads":8}]}}`
+
+	httpC := newHTTPClient(t.Name())
+	registerProcessEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/new"
+	resp, err := httpC.Post(registerProcessEndpoint, "application/json", bytes.NewBufferString(`{"user_metadata": `+metadata+`}`))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+
+	getProcessEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/" + cpr.Data.ID.String()
+	resp, err = httpC.Get(getProcessEndpoint)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+
+	var raw struct {
+		Data struct {
+			Metadata json.RawMessage `json:"metadata"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &raw))
+	assert.Equal(t, `{"checkpoint":null,"empty":{},"layers":[],"lr":0.001,"model":{"dims":[512,{"heads":8}]},"seed":9223372036854775807,"tokens":123456789012345678901234567890,"warmup":1.0}`, string(raw.Data.Metadata))
+}
+
+type getModelMetricsResponse struct {
+	middleware.ResponseWrapper
+	Data GetModelMetricsResponse `json:"data"`
+}
+
+func TestAppStoresNonFiniteMetricValues(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+
+	resp, err = httpC.Post(baseURL+"/process/"+cpr.Data.ID.String()+"/model-metrics", "application/json", bytes.NewBufferString(`[
+		{"step_name": "step", "step_value": 1, "metrics": {"loss": 2.5}},
+		{"step_name": "step", "step_value": 2, "metrics": {"loss": NaN}},
+		{"step_name": "step", "step_value": 3, "metrics": {"loss": Infinity}}
+	]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = httpC.Post(baseURL+"/processes/model-metrics", "application/json", bytes.NewBufferString(`["`+cpr.Data.ID.String()+`"]`))
+	require.NoError(t, err)
+	gmr := read[getModelMetricsResponse](t, resp)
+	require.Len(t, gmr.Data.Sections["default"], 1)
+	series := gmr.Data.Sections["default"][0].Series
+	require.Len(t, series, 2)
+	assert.Equal(t, []interface{}{1.0, 2.0, 3.0}, series[0].Values)
+	assert.Equal(t, []interface{}{2.5, nil, nil}, series[1].Values)
+	assert.Equal(t, &FieldEntities{NaN: []int{1}, Inf: []int{2}}, series[1].Entities)
+}
+
+func TestAppRejectsMetricsOfOtherTenantsProcesses(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+
+	// A process of another tenant.
+	otherID := uuid.New()
+	require.NoError(t, testApp.db(context.Background()).Create(&model.Process{
+		ID:        otherID,
+		TenantID:  "other",
+		Status:    model.ProcessStatusRunning,
+		StartTime: time.Now(),
+	}).Error)
+
+	resp, err := httpC.Post(baseURL+"/process/"+otherID.String()+"/model-metrics", "application/json", bytes.NewBufferString(`[
+		{"step_name": "step", "step_value": 1, "metrics": {"loss": 2.5}}
+	]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	var count int64
+	require.NoError(t, testApp.db(context.Background()).Model(&model.ModelMetrics{}).Where("process_id = ?", otherID).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestAppPlotsMetricsAgainstTime(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+	start := cpr.Data.StartTime
+
+	first := start.Add(10 * time.Second)
+	second := start.Add(20 * time.Second)
+	resp, err = httpC.Post(baseURL+"/process/"+cpr.Data.ID.String()+"/model-metrics", "application/json", bytes.NewBufferString(fmt.Sprintf(`[
+		{"step_name": "step", "step_value": 1, "timestamp": %q, "metrics": {"loss": 2}},
+		{"step_name": "step", "step_value": 2, "timestamp": %f, "metrics": {"loss": 1}}
+	]`, first.Format(time.RFC3339Nano), float64(second.UnixNano())/1e9)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	modelMetricsEndpoint := baseURL + "/processes/model-metrics"
+	body := `["` + cpr.Data.ID.String() + `"]`
+	resp, err = httpC.Post(modelMetricsEndpoint+"?x_axis=time", "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	gmr := read[getModelMetricsResponse](t, resp)
+	require.Len(t, gmr.Data.Sections["default"], 1)
+	series := gmr.Data.Sections["default"][0].Series
+	assert.Equal(t, "time", series[0].Type)
+	assert.Equal(t, []interface{}{float64(first.UnixMilli()), float64(second.UnixMilli())}, series[0].Values)
+	assert.Equal(t, []interface{}{2.0, 1.0}, series[1].Values)
+
+	resp, err = httpC.Post(modelMetricsEndpoint+"?x_axis=relative_time", "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	gmr = read[getModelMetricsResponse](t, resp)
+	series = gmr.Data.Sections["default"][0].Series
+	require.Len(t, series[0].Values, 2)
+	assert.InDelta(t, 10.0, series[0].Values[0], 0.001)
+	assert.InDelta(t, 20.0, series[0].Values[1], 0.001)
+
+	// Points without a timestamp are stamped when they are received.
+	resp, err = httpC.Post(baseURL+"/process/"+cpr.Data.ID.String()+"/model-metrics", "application/json",
+		bytes.NewBufferString(`[{"step_name": "step", "step_value": 3, "metrics": {"loss": 0.5}}]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	resp, err = httpC.Post(modelMetricsEndpoint+"?x_axis=time", "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	gmr = read[getModelMetricsResponse](t, resp)
+	assert.Len(t, gmr.Data.Sections["default"][0].Series[0].Values, 3)
+
+	resp, err = httpC.Post(modelMetricsEndpoint+"?x_axis=epoch", "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAppPlotsMetricsAgainstAxes(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+
+	resp, err = httpC.Post(baseURL+"/process/"+cpr.Data.ID.String()+"/model-metrics", "application/json", bytes.NewBufferString(`[
+		{"step_name": "step", "step_value": 1, "axes": {"epoch": 0.5, "tokens": 1000}, "metrics": {"loss": 2, "accuracy": 0.1}},
+		{"step_name": "step", "step_value": 2, "axes": {"epoch": 1, "tokens": 2000}, "metrics": {"loss": 1, "accuracy": 0.2}},
+		{"step_name": "step", "step_value": 3, "metrics": {"loss": 0.5}},
+		{"step_name": "epoch", "step_value": 1, "metrics": {"val_loss": 1.5}}
+	]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	modelMetricsEndpoint := baseURL + "/processes/model-metrics"
+	body := `["` + cpr.Data.ID.String() + `"]`
+	resp, err = httpC.Post(modelMetricsEndpoint+"?axis=epoch", "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	gmr := read[getModelMetricsResponse](t, resp)
+	panels := map[string]DataFrame{}
+	for _, panel := range gmr.Data.Sections["default"] {
+		panels[panel.Title] = panel.Series
+	}
+	require.Len(t, panels, 3)
+	// Points without the axis are left out, points logged by epoch are at
+	// their step.
+	assert.Equal(t, "epoch", panels["loss"][0].Name)
+	assert.Equal(t, []interface{}{0.5, 1.0}, panels["loss"][0].Values)
+	assert.Equal(t, []interface{}{2.0, 1.0}, panels["loss"][1].Values)
+	assert.Equal(t, []interface{}{0.1, 0.2}, panels["accuracy"][1].Values)
+	assert.Equal(t, []interface{}{1.0}, panels["val_loss"][0].Values)
+
+	resp, err = httpC.Post(modelMetricsEndpoint+"?axis=tokens", "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	gmr = read[getModelMetricsResponse](t, resp)
+	require.Len(t, gmr.Data.Sections["default"], 2)
+
+	resp, err = httpC.Post(modelMetricsEndpoint+"?axis=epoch&x_axis=time", "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// The step name can't also be an axis.
+	resp, err = httpC.Post(baseURL+"/process/"+cpr.Data.ID.String()+"/model-metrics", "application/json",
+		bytes.NewBufferString(`[{"step_name": "step", "step_value": 4, "axes": {"step": 4}, "metrics": {"loss": 0.1}}]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAppHandlesMetricConflicts(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+	metricsEndpoint := baseURL + "/process/" + cpr.Data.ID.String() + "/model-metrics"
+
+	type counts struct {
+		MetricsCreated     int `json:"metricsCreated"`
+		MetricsSkipped     int `json:"metricsSkipped"`
+		MetricsOverwritten int `json:"metricsOverwritten"`
+	}
+	type countsResponse struct {
+		middleware.ResponseWrapper
+		Data counts `json:"data"`
+	}
+	post := func(query, body string) *http.Response {
+		resp, err := httpC.Post(metricsEndpoint+query, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		return resp
+	}
+	loss := func() []interface{} {
+		resp, err := httpC.Post(baseURL+"/processes/model-metrics", "application/json", bytes.NewBufferString(`["`+cpr.Data.ID.String()+`"]`))
+		require.NoError(t, err)
+		gmr := read[getModelMetricsResponse](t, resp)
+		return gmr.Data.Sections["default"][0].Series[1].Values
+	}
+
+	first := `[{"step_name": "step", "step_value": 1, "metrics": {"loss": 2}}]`
+	c := read[countsResponse](t, post("", first))
+	assert.Equal(t, counts{MetricsCreated: 1}, c.Data)
+
+	// A retry conflicts by default and leaves everything as it was.
+	retry := `[{"step_name": "step", "step_value": 1, "metrics": {"loss": 3}}, {"step_name": "step", "step_value": 2, "metrics": {"loss": 1}}]`
+	resp = post("", retry)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, []interface{}{2.0}, loss())
+
+	c = read[countsResponse](t, post("?on_conflict=ignore", retry))
+	assert.Equal(t, counts{MetricsCreated: 1, MetricsSkipped: 1}, c.Data)
+	assert.Equal(t, []interface{}{2.0, 1.0}, loss())
+
+	c = read[countsResponse](t, post("?on_conflict=overwrite", retry))
+	assert.Equal(t, counts{MetricsOverwritten: 2}, c.Data)
+	assert.Equal(t, []interface{}{3.0, 1.0}, loss())
+
+	// Within a request, the last value wins when overwriting.
+	twice := `[{"step_name": "step", "step_value": 3, "metrics": {"loss": 5}}, {"step_name": "step", "step_value": 3, "metrics": {"loss": 0.5}}]`
+	resp = post("", twice)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	c = read[countsResponse](t, post("?on_conflict=overwrite", twice))
+	assert.Equal(t, counts{MetricsCreated: 1, MetricsOverwritten: 1}, c.Data)
+	assert.Equal(t, []interface{}{3.0, 1.0, 0.5}, loss())
+
+	resp = post("?on_conflict=merge", first)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAppLimitsAndInstrumentsMetricIngestion(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	serverURL := "http://" + testApp.server.HTTPListenAddr().String()
+	baseURL := serverURL + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+	metricsEndpoint := baseURL + "/process/" + cpr.Data.ID.String() + "/model-metrics"
+
+	// More points than fit in one batch.
+	points := make([]string, 0, 2*insertBatchSize+1)
+	for step := 1; step <= cap(points); step++ {
+		points = append(points, fmt.Sprintf(`{"step_name": "step", "step_value": %d, "metrics": {"loss": %d}}`, step, step))
+	}
+	resp, err = httpC.Post(metricsEndpoint, "application/json", bytes.NewBufferString("["+strings.Join(points, ",")+"]"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = httpC.Post(baseURL+"/processes/model-metrics", "application/json", bytes.NewBufferString(`["`+cpr.Data.ID.String()+`"]`))
+	require.NoError(t, err)
+	gmr := read[getModelMetricsResponse](t, resp)
+	assert.Len(t, gmr.Data.Sections["default"][0].Series[0].Values, len(points))
+
+	// The test app accepts up to 1MiB.
+	large := `[{"step_name": "step", "step_value": 1, "metrics": {"loss": 1}}` + strings.Repeat(" ", 1<<20) + "]"
+	resp, err = httpC.Post(metricsEndpoint, "application/json", bytes.NewBufferString(large))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp, err = httpC.Get(serverURL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "ai_training_api_model_metrics_ingest_duration_seconds_count 2")
+	assert.Contains(t, string(body), fmt.Sprintf("ai_training_api_model_metrics_ingest_rows_sum %d", len(points)))
+}
+
+func TestAppStreamsNDJSONMetrics(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+	metricsEndpoint := baseURL + "/process/" + cpr.Data.ID.String() + "/model-metrics"
+
+	type streamResponse struct {
+		middleware.ResponseWrapper
+		Data struct {
+			MetricsCreated int         `json:"metricsCreated"`
+			LinesSaved     int         `json:"linesSaved"`
+			LinesRejected  int         `json:"linesRejected"`
+			LineErrors     []LineError `json:"lineErrors"`
+		} `json:"data"`
+	}
+	post := func(contentType string, gzipped bool, body string) *http.Response {
+		var buf bytes.Buffer
+		if gzipped {
+			gz := gzip.NewWriter(&buf)
+			_, err := gz.Write([]byte(body))
+			require.NoError(t, err)
+			require.NoError(t, gz.Close())
+		} else {
+			buf.WriteString(body)
+		}
+		req, err := http.NewRequest(http.MethodPost, metricsEndpoint, &buf)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		if gzipped {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		resp, err := httpC.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// More points than fit in one chunk, with invalid lines in between.
+	lines := make([]string, 0, streamChunkPoints+3)
+	for step := 1; step <= streamChunkPoints+1; step++ {
+		lines = append(lines, fmt.Sprintf(`{"step_name": "step", "step_value": %d, "metrics": {"loss": %d}}`, step, step))
+	}
+	lines = slices.Insert(lines, 2, `{"step_name": "step", "step_value": 0, "metrics": {"loss": 1}}`, "", `{"step_name": `)
+	sr := read[streamResponse](t, post("application/x-ndjson", true, strings.Join(lines, "\n")+"\n"))
+	assert.Equal(t, streamChunkPoints+1, sr.Data.MetricsCreated)
+	assert.Equal(t, 2, sr.Data.LinesRejected)
+	require.Len(t, sr.Data.LineErrors, 2)
+	assert.Equal(t, 3, sr.Data.LineErrors[0].Line)
+	assert.Contains(t, sr.Data.LineErrors[0].Error, "step must be a positive number")
+	assert.Equal(t, 5, sr.Data.LineErrors[1].Line)
+	assert.Contains(t, sr.Data.LineErrors[1].Error, "invalid JSON")
+
+	// Gzipped JSON arrays are accepted too.
+	resp = post("application/json", true, `[{"step_name": "epoch", "step_value": 1, "metrics": {"val_loss": 1}}]`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = httpC.Post(baseURL+"/processes/model-metrics", "application/json", bytes.NewBufferString(`["`+cpr.Data.ID.String()+`"]`))
+	require.NoError(t, err)
+	gmr := read[getModelMetricsResponse](t, resp)
+	panels := map[string]int{}
+	for _, panel := range gmr.Data.Sections["default"] {
+		panels[panel.Title] = len(panel.Series[0].Values)
+	}
+	assert.Equal(t, map[string]int{"loss": streamChunkPoints + 1, "val_loss": 1}, panels)
+
+	resp = post("application/x-ndjson", false, `{"step_name": "step", "step_value": 1, "metrics": {"loss": 1}}`+"\n"+strings.Repeat(" ", 1<<20)+"\n")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	for _, encoding := range []string{"gzip", "br"} {
+		req, err := http.NewRequest(http.MethodPost, metricsEndpoint, bytes.NewBufferString("[]"))
+		require.NoError(t, err)
+		req.Header.Set("Content-Encoding", encoding)
+		resp, err = httpC.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, encoding)
+	}
+
+	// Saving the second chunk fails, the first one is kept and counted in
+	// the error response.
+	resp = post("application/json", false, fmt.Sprintf(`[{"step_name": "step", "step_value": %d, "metrics": {"acc": 1}}]`, streamChunkPoints+1))
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	lines = lines[:0]
+	for step := 1; step <= streamChunkPoints+1; step++ {
+		lines = append(lines, fmt.Sprintf(`{"step_name": "step", "step_value": %d, "metrics": {"acc": %d}}`, step, step))
+	}
+	resp = post("application/x-ndjson", false, strings.Join(lines, "\n")+"\n")
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, resp.StatusCode, string(body))
+	var partial streamResponse
+	require.NoError(t, json.Unmarshal(body, &partial))
+	assert.Equal(t, "error", partial.Status)
+	assert.Equal(t, streamChunkPoints, partial.Data.MetricsCreated)
+	assert.Equal(t, streamChunkPoints, partial.Data.LinesSaved)
+
+	resp, err = httpC.Post(baseURL+"/processes/model-metrics", "application/json", bytes.NewBufferString(`["`+cpr.Data.ID.String()+`"]`))
+	require.NoError(t, err)
+	gmr = read[getModelMetricsResponse](t, resp)
+	for _, panel := range gmr.Data.Sections["default"] {
+		panels[panel.Title] = len(panel.Series[0].Values)
+	}
+	assert.Equal(t, streamChunkPoints+1, panels["acc"])
+}
+
+func TestAppDownsamplesModelMetrics(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+
+	// A slowly decreasing loss that blows up once.
+	var lines []string
+	for step := 1; step <= 2000; step++ {
+		loss := 1 / float64(step)
+		if step == 1234 {
+			loss = 100
+		}
+		lines = append(lines, fmt.Sprintf(`{"step_name": "step", "step_value": %d, "metrics": {"loss": %g}}`, step, loss))
+	}
+	resp, err = httpC.Post(baseURL+"/process/"+cpr.Data.ID.String()+"/model-metrics", contentTypeNDJSON, bytes.NewBufferString(strings.Join(lines, "\n")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	modelMetricsEndpoint := baseURL + "/processes/model-metrics"
+	body := `["` + cpr.Data.ID.String() + `"]`
+	for _, method := range []string{"", downsampleMax} {
+		resp, err = httpC.Post(modelMetricsEndpoint+"?max_data_points=100&downsample="+method, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		gmr := read[getModelMetricsResponse](t, resp)
+		series := gmr.Data.Sections["default"][0].Series
+		assert.LessOrEqual(t, len(series[0].Values), 100, method)
+		assert.Contains(t, series[1].Values, float64(100), method)
+	}
+
+	for _, query := range []string{"?max_data_points=1", "?max_data_points=many", "?max_data_points=100&downsample=median", "?downsample=avg"} {
+		resp, err = httpC.Post(modelMetricsEndpoint+query, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func TestAppAlignsModelMetrics(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	var processIDs []string
+	// One process logs every other step, the other every step from step 2.
+	for _, steps := range [][]int{{1, 3, 5}, {2, 3, 4}} {
+		resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+		require.NoError(t, err)
+		cpr := read[createProcessResponse](t, resp)
+		processIDs = append(processIDs, cpr.Data.ID.String())
+
+		var lines []string
+		for _, step := range steps {
+			lines = append(lines, fmt.Sprintf(`{"step_name": "step", "step_value": %d, "metrics": {"loss": %d}}`, step, 10*step))
+		}
+		resp, err = httpC.Post(baseURL+"/process/"+cpr.Data.ID.String()+"/model-metrics", contentTypeNDJSON, bytes.NewBufferString(strings.Join(lines, "\n")))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	modelMetricsEndpoint := baseURL + "/processes/model-metrics"
+	body := `["` + strings.Join(processIDs, `", "`) + `"]`
+	values := func(series DataFrame) map[string][]interface{} {
+		byName := map[string][]interface{}{}
+		for _, field := range series {
+			byName[field.Name] = field.Values
+		}
+		return byName
+	}
+	tests := map[string]map[string][]interface{}{
+		"": {
+			"step":        {float64(1), float64(2), float64(3), float64(4), float64(5)},
+			processIDs[0]: {float64(10), nil, float64(30), nil, float64(50)},
+			processIDs[1]: {nil, float64(20), float64(30), float64(40), nil},
+		},
+		alignIntersection: {
+			"step":        {float64(3)},
+			processIDs[0]: {float64(30)},
+			processIDs[1]: {float64(30)},
+		},
+		alignInterpolate: {
+			"step":        {float64(1), float64(2), float64(3), float64(4), float64(5)},
+			processIDs[0]: {float64(10), float64(20), float64(30), float64(40), float64(50)},
+			processIDs[1]: {nil, float64(20), float64(30), float64(40), nil},
+		},
+	}
+	for align, want := range tests {
+		resp, err := httpC.Post(modelMetricsEndpoint+"?align="+align, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		gmr := read[getModelMetricsResponse](t, resp)
+		require.Len(t, gmr.Data.Sections["default"], 1)
+		assert.Equal(t, want, values(gmr.Data.Sections["default"][0].Series), align)
+	}
+
+	resp, err := httpC.Post(modelMetricsEndpoint+"?align=nearest", "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAppSmoothsModelMetrics(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+	processID := cpr.Data.ID.String()
+
+	// A loss alternating between 1 and 3.
+	var lines []string
+	for step := 1; step <= 200; step++ {
+		lines = append(lines, fmt.Sprintf(`{"step_name": "step", "step_value": %d, "metrics": {"loss": %d}}`, step, 1+2*(step%2)))
+	}
+	resp, err = httpC.Post(baseURL+"/process/"+processID+"/model-metrics", contentTypeNDJSON, bytes.NewBufferString(strings.Join(lines, "\n")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	modelMetricsEndpoint := baseURL + "/processes/model-metrics"
+	body := `["` + processID + `"]`
+	for _, query := range []string{"?smoothing=moving_average&smoothing_window=2", "?smoothing=moving_average&smoothing_window=2&max_data_points=50"} {
+		resp, err = httpC.Post(modelMetricsEndpoint+query, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		gmr := read[getModelMetricsResponse](t, resp)
+		series := gmr.Data.Sections["default"][0].Series
+		require.Len(t, series, 3, query)
+		assert.Equal(t, processID, series[1].Name, query)
+		assert.Nil(t, series[1].Labels, query)
+		// Smoothed series have names of their own, as clients key series
+		// by name.
+		assert.Equal(t, processID+" (moving_average)", series[2].Name, query)
+		assert.Equal(t, map[string]string{smoothingLabel: smoothingMovingAverage, smoothedLabel: processID}, series[2].Labels, query)
+		// Past the first step, the average of the last two points is 2.
+		assert.Equal(t, float64(2), series[2].Values[len(series[2].Values)-1], query)
+	}
+
+	for _, query := range []string{"?smoothing=median", "?smoothing=ema&smoothing_weight=2", "?smoothing_sigma=2"} {
+		resp, err = httpC.Post(modelMetricsEndpoint+query, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func TestAppAggregatesGroupModelMetrics(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	// Three seeds of the same run, and a process outside the group.
+	var groupID string
+	for seed, body := range []string{sampleProcessWithGroupNameJSON, sampleProcessWithGroupNameJSON, sampleProcessWithGroupNameJSON, sampleProcessNestedJSON} {
+		resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		cpr := read[createProcessResponse](t, resp)
+		if cpr.Data.GroupID != nil {
+			groupID = cpr.Data.GroupID.String()
+		}
+
+		var lines []string
+		for step := 1; step <= 2; step++ {
+			lines = append(lines, fmt.Sprintf(`{"step_name": "step", "step_value": %d, "metrics": {"train/loss": %d, "lr": 0.1}}`, step, 10*(seed+1)+step))
+		}
+		resp, err = httpC.Post(baseURL+"/process/"+cpr.Data.ID.String()+"/model-metrics", contentTypeNDJSON, bytes.NewBufferString(strings.Join(lines, "\n")))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	require.NotEmpty(t, groupID)
+
+	groupMetricsEndpoint := baseURL + "/group/" + groupID + "/model-metrics"
+	resp, err := httpC.Post(groupMetricsEndpoint+"?percentiles=50", "application/json", bytes.NewBufferString(`{"metrics": ["train/*"]}`))
+	require.NoError(t, err)
+	gmr := read[getModelMetricsResponse](t, resp)
+	require.Empty(t, gmr.Data.Sections["default"])
+	require.Len(t, gmr.Data.Sections["train"], 1)
+	panel := gmr.Data.Sections["train"][0]
+	assert.Equal(t, "loss", panel.Title)
+	values := map[string][]interface{}{}
+	for _, field := range panel.Series {
+		values[field.Name] = field.Values
+	}
+	assert.Equal(t, map[string][]interface{}{
+		"step":   {float64(1), float64(2)},
+		"count":  {float64(3), float64(3)},
+		"mean":   {float64(21), float64(22)},
+		"median": {float64(21), float64(22)},
+		"min":    {float64(11), float64(12)},
+		"max":    {float64(31), float64(32)},
+		"std":    {float64(10), float64(10)},
+		"p50":    {float64(21), float64(22)},
+	}, values)
+
+	// Without a body, all metrics are aggregated.
+	resp, err = httpC.Post(groupMetricsEndpoint, "application/json", nil)
+	require.NoError(t, err)
+	gmr = read[getModelMetricsResponse](t, resp)
+	assert.Len(t, gmr.Data.Sections["train"], 1)
+	assert.Len(t, gmr.Data.Sections["default"], 1)
+
+	for _, query := range []string{"?percentiles=200", "?align=nearest"} {
+		resp, err = httpC.Post(groupMetricsEndpoint+query, "application/json", nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+	// Processes can't be selected, they are the members of the group.
+	resp, err = httpC.Post(groupMetricsEndpoint, "application/json", bytes.NewBufferString(`["`+uuid.NewString()+`"]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, err = httpC.Post(baseURL+"/group/"+uuid.NewString()+"/model-metrics", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestAppSummarizesModelMetrics(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+	processID := cpr.Data.ID
+
+	// The loss diverges at the last step, the learning rate is constant.
+	lines := []string{
+		`{"step_name": "step", "step_value": 1, "metrics": {"loss": 4, "lr": 0.5}}`,
+		`{"step_name": "step", "step_value": 2, "metrics": {"loss": 1, "lr": 0.5}}`,
+		`{"step_name": "step", "step_value": 3, "metrics": {"loss": 3, "lr": 0.5}}`,
+		`{"step_name": "step", "step_value": 4, "metrics": {"loss": NaN, "lr": 0.5}}`,
+		`{"step_name": "epoch", "step_value": 1, "metrics": {"loss": 2}}`,
+	}
+	resp, err = httpC.Post(baseURL+"/process/"+processID.String()+"/model-metrics", contentTypeNDJSON, bytes.NewBufferString(strings.Join(lines, "\n")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	type summariesResponse struct {
+		middleware.ResponseWrapper
+		Data []MetricSummary `json:"data"`
+	}
+	step := func(s uint32) *uint32 { return &s }
+	value := func(v float64) *MetricValue { m := MetricValue(v); return &m }
+	lossSummary := MetricSummary{
+		ProcessID: processID, MetricName: "loss", StepName: "step",
+		Count: 4, NonFiniteCount: 1, FirstStep: 1, LastStep: 4,
+		Min: value(1), MinStep: step(2), Max: value(4), MaxStep: step(1), Mean: value(8.0 / 3),
+	}
+
+	resp, err = httpC.Post(baseURL+"/processes/metric-summaries", "application/json",
+		bytes.NewBufferString(`{"process_ids": ["`+processID.String()+`"], "metrics": ["loss"], "step_names": ["step"]}`))
+	require.NoError(t, err)
+	sr := read[summariesResponse](t, resp)
+	require.Len(t, sr.Data, 1)
+	assert.True(t, math.IsNaN(float64(sr.Data[0].Last)))
+	sr.Data[0].Last = 0
+	assert.Equal(t, lossSummary, sr.Data[0])
+
+	// The bounds of the steps apply to the summaries.
+	resp, err = httpC.Post(baseURL+"/processes/metric-summaries", "application/json",
+		bytes.NewBufferString(`{"process_ids": ["`+processID.String()+`"], "metrics": ["lr"], "max_step": 3}`))
+	require.NoError(t, err)
+	sr = read[summariesResponse](t, resp)
+	require.Len(t, sr.Data, 1)
+	assert.Equal(t, MetricSummary{
+		ProcessID: processID, MetricName: "lr", StepName: "step",
+		Count: 3, FirstStep: 1, LastStep: 3, Last: 0.5,
+		Min: value(0.5), MinStep: step(1), Max: value(0.5), MaxStep: step(1), Mean: value(0.5),
+	}, sr.Data[0])
+
+	type processWithSummaries struct {
+		ID              uuid.UUID       `json:"process_uuid"`
+		MetricSummaries []MetricSummary `json:"metric_summaries"`
+	}
+	for _, path := range []string{"/process/" + processID.String(), "/process/" + processID.String() + "?metadata=flat"} {
+		resp, err = httpC.Get(baseURL + path)
+		require.NoError(t, err)
+		pr := read[middleware.ResponseWrapper](t, resp)
+		assert.NotContains(t, pr.Data, "metric_summaries", path)
+
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		resp, err = httpC.Get(baseURL + path + sep + "metric_summaries=true")
+		require.NoError(t, err)
+		gpr := read[struct {
+			middleware.ResponseWrapper
+			Data processWithSummaries `json:"data"`
+		}](t, resp)
+		assert.Equal(t, processID, gpr.Data.ID, path)
+		// loss against epoch and step, and lr.
+		assert.Len(t, gpr.Data.MetricSummaries, 3, path)
+	}
+
+	resp, err = httpC.Get(baseURL + "/processes?metric_summaries=true")
+	require.NoError(t, err)
+	lpr := read[struct {
+		middleware.ResponseWrapper
+		Data []processWithSummaries `json:"data"`
+	}](t, resp)
+	require.Len(t, lpr.Data, 1)
+	assert.Len(t, lpr.Data[0].MetricSummaries, 3)
+
+	for _, path := range []string{"/processes?metric_summaries=maybe", "/process/" + processID.String() + "?metric_summaries=2"} {
+		resp, err = httpC.Get(baseURL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, path)
+	}
+}
+
+func TestAppSelectsModelMetrics(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+
+	var lines []string
+	for step := 1; step <= 10; step++ {
+		lines = append(lines, fmt.Sprintf(`{"step_name": "step", "step_value": %d, "metrics": {"train/loss": 1, "train/lr": 1, "Train/loss": 1, "val/loss": 1, "tokens_100%%": 1, "loss": 1}}`, step))
+	}
+	lines = append(lines, `{"step_name": "epoch", "step_value": 1, "metrics": {"train/loss": 1, "val/loss": 1}}`)
+	resp, err = httpC.Post(baseURL+"/process/"+cpr.Data.ID.String()+"/model-metrics", contentTypeNDJSON, bytes.NewBufferString(strings.Join(lines, "\n")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// panels returns the number of steps of each panel, by section and
+	// title.
+	panels := func(query string) map[string]int {
+		resp, err := httpC.Post(baseURL+"/processes/model-metrics", "application/json", bytes.NewBufferString(query))
+		require.NoError(t, err)
+		gmr := read[getModelMetricsResponse](t, resp)
+		steps := map[string]int{}
+		for section, sectionPanels := range gmr.Data.Sections {
+			for _, panel := range sectionPanels {
+				steps[section+"/"+panel.Title+"/"+panel.Series[0].Name] = len(panel.Series[0].Values)
+			}
+		}
+		return steps
+	}
+	processID := `"process_ids": ["` + cpr.Data.ID.String() + `"]`
+
+	assert.Len(t, panels(`["`+cpr.Data.ID.String()+`"]`), 8)
+	assert.Equal(t, map[string]int{"train/loss/step": 10, "train/lr/step": 10, "train/loss/epoch": 1},
+		panels(`{`+processID+`, "metrics": ["train/*"]}`))
+	assert.Equal(t, map[string]int{"train/loss/step": 3, "Train/loss/step": 3, "val/loss/step": 3},
+		panels(`{`+processID+`, "metrics": ["*/loss"], "step_names": ["step"], "min_step": 4, "max_step": 6}`))
+	assert.Equal(t, map[string]int{"default/loss/step": 10, "default/tokens_100%/step": 10},
+		panels(`{`+processID+`, "sections": ["default"]}`))
+	assert.Equal(t, map[string]int{"val/loss/epoch": 1, "val/loss/step": 1, "default/tokens_100%/step": 1},
+		panels(`{`+processID+`, "metrics": ["tokens_100%", "val/?oss", "train/loss"], "sections": ["val", "default"], "step_names": ["epoch", "step"], "min_step": 1, "max_step": 1}`))
+	assert.Empty(t, panels(`{`+processID+`, "metrics": ["tokens_1000"]}`))
+
+	for _, query := range []string{
+		`{` + processID + `, "min_step": 5, "max_step": 4}`,
+		`{` + processID + `, "metrics": [""]}`,
+		`{` + processID + `, "metric": ["loss"]}`,
+		`{"process_ids": ["not a UUID"]}`,
+	} {
+		resp, err = httpC.Post(baseURL+"/processes/model-metrics", "application/json", bytes.NewBufferString(query))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -12,9 +13,12 @@ import (
 	"github.com/grafana/dskit/server"
 	"github.com/mattn/go-sqlite3"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/promlog"
+	"google.golang.org/grpc"
 	"gorm.io/gorm"
 
+	"github.com/grafana/ai-training-o11y/ai-training-api/ingestpb"
 	db "github.com/grafana/ai-training-o11y/ai-training-api/internal"
 	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
 	"github.com/grafana/ai-training-o11y/ai-training-api/model"
@@ -22,6 +26,9 @@ import (
 
 const (
 	metricsNamespace = "ai_training_api"
+
+	// Responses of the gRPC API are small, this is gRPC's default.
+	grpcMaxSendMsgSize = 4 << 20
 )
 
 // App is the main application struct.
@@ -36,17 +43,41 @@ type App struct {
 	lokiAddress string
 	lokiTenant  string
 
+	// Heartbeat timeouts after which running processes are marked as
+	// crashed, with optional per-tenant overrides.
+	defaultHeartbeatTimeout time.Duration
+	tenantHeartbeatTimeouts map[string]time.Duration
+	// Controls the lifetime of the stale process reaper.
+	reaperCtx  context.Context
+	stopReaper context.CancelFunc
+
+	// Largest model metrics request body accepted, in bytes.
+	maxMetricsPayloadBytes int64
+	// Instrumentation of model metrics ingestion.
+	ingestDuration prometheus.Histogram
+	ingestRows     prometheus.Histogram
+	// Label conventions of Prometheus remote-write requests.
+	remoteWriteConfig RemoteWriteConfig
+	// Attribute conventions of OTLP metrics.
+	otlpConfig OTLPConfig
+
 	logger log.Logger
 }
 
 func New(
 	listenAddress string,
 	listenPort int,
+	grpcListenPort int,
 	databaseAddress string,
 	databaseType string,
 	constTenant string,
 	lokiAddress string,
 	lokiTenant string,
+	heartbeatTimeout time.Duration,
+	tenantHeartbeatTimeouts map[string]time.Duration,
+	maxMetricsPayloadBytes int64,
+	remoteWriteConfig RemoteWriteConfig,
+	otlpConfig OTLPConfig,
 	promlogConfig *promlog.Config) (*App, error) {
 	// Initialize observability constructs.
 	logger := promlog.New(promlogConfig)
@@ -77,30 +108,78 @@ func New(
 	}
 	level.Info(logger).Log("msg", "checking tables", "group_table_exists", db.Migrator().HasTable(&model.Group{}))
 
+	backfilled, err := backfillMetadataNumbers(db)
+	if err != nil {
+		return nil, err
+	}
+	if backfilled > 0 {
+		level.Info(logger).Log("msg", "backfilled numeric metadata", "rows", backfilled)
+	}
+
 	err = db.AutoMigrate(&model.MetadataKV{})
 	if err != nil {
 		return nil, fmt.Errorf("error migrating MetadataKV table: %w", err)
 	}
 	level.Info(logger).Log("msg", "checking tables", "metadata_kv_table_exists", db.Migrator().HasTable(&model.MetadataKV{}))
 
+	err = db.AutoMigrate(&model.MetadataHistory{})
+	if err != nil {
+		return nil, fmt.Errorf("error migrating MetadataHistory table: %w", err)
+	}
+	level.Info(logger).Log("msg", "checking tables", "metadata_history_table_exists", db.Migrator().HasTable(&model.MetadataHistory{}))
+
+	backfilled, err = backfillMetadataHistory(db)
+	if err != nil {
+		return nil, err
+	}
+	if backfilled > 0 {
+		level.Info(logger).Log("msg", "backfilled metadata history", "rows", backfilled)
+	}
+
+	converted, err := migrateMetricValues(db)
+	if err != nil {
+		return nil, err
+	}
+	if converted > 0 {
+		level.Info(logger).Log("msg", "converted metric values to numbers", "rows", converted)
+	}
+
 	err = db.AutoMigrate(&model.ModelMetrics{})
 	if err != nil {
 		return nil, fmt.Errorf("error migrating ModelMetrics table: %w", err)
 	}
 	level.Info(logger).Log("msg", "checking tables", "model_metrics_table_exists", db.Migrator().HasTable(&model.MetadataKV{}))
 
+	err = db.AutoMigrate(&model.MetricAxis{})
+	if err != nil {
+		return nil, fmt.Errorf("error migrating MetricAxis table: %w", err)
+	}
+	level.Info(logger).Log("msg", "checking tables", "metric_axis_table_exists", db.Migrator().HasTable(&model.MetricAxis{}))
+
 	// Create server and router.
 	serverLogLevel := &dskit_log.Level{}
 	serverLogLevel.Set(promlogConfig.Level.String())
 	// Create a prometheus registry to avoid "duplicate metrics collector registration attempted"
-	// errors when running tests.
+	// errors when running tests. It is exposed along with the globally
+	// registered metrics, e.g. the version and database ones.
 	reg := prometheus.NewRegistry()
+	grpcAuthn, grpcStreamAuthn := middleware.GRPCAuthnMiddleware(constTenant)
 	s, err := server.New(server.Config{
 		Registerer:        reg,
+		Gatherer:          prometheus.Gatherers{reg, prometheus.DefaultGatherer},
 		MetricsNamespace:  metricsNamespace,
 		HTTPListenAddress: listenAddress,
 		HTTPListenPort:    listenPort,
+		GRPCListenAddress: listenAddress,
+		GRPCListenPort:    grpcListenPort,
 		LogLevel:          *serverLogLevel,
+		// Authenticate gRPC requests like HTTP ones, see AuthnMiddleware.
+		GRPCMiddleware:       []grpc.UnaryServerInterceptor{grpcAuthn},
+		GRPCStreamMiddleware: []grpc.StreamServerInterceptor{grpcStreamAuthn},
+		// Like HTTP bodies, each message is limited to the metrics payload
+		// size.
+		GRPCServerMaxRecvMsgSize: int(maxMetricsPayloadBytes),
+		GRPCServerMaxSendMsgSize: grpcMaxSendMsgSize,
 		// We get a lot of server side instrumentation for "free" using dskit's middleware
 		// for logs and metrics.
 		RegisterInstrumentation: true,
@@ -117,8 +196,30 @@ func New(
 		server:      s,
 		lokiAddress: lokiAddress,
 		lokiTenant:  lokiTenant,
-		logger:      logger,
+
+		defaultHeartbeatTimeout: heartbeatTimeout,
+		tenantHeartbeatTimeouts: tenantHeartbeatTimeouts,
+
+		maxMetricsPayloadBytes: maxMetricsPayloadBytes,
+		ingestDuration: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "model_metrics_ingest_duration_seconds",
+			Help:      "Time taken to ingest a model metrics request.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		ingestRows: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "model_metrics_ingest_rows",
+			Help:      "Number of metric points per model metrics request.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+		}),
+
+		remoteWriteConfig: remoteWriteConfig,
+		otlpConfig:        otlpConfig,
+
+		logger: logger,
 	}
+	a.reaperCtx, a.stopReaper = context.WithCancel(context.Background())
 
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -135,6 +236,15 @@ func New(
 	router.Use(middleware.AuthnMiddleware(constTenant))
 	a.registerAPI(router)
 
+	// Register the OTLP/HTTP metrics receiver, at the path OTLP exporters
+	// send metrics to by default.
+	otlpRouter := a.server.HTTP.PathPrefix("/v1").Subrouter()
+	otlpRouter.Use(middleware.AuthnMiddleware(constTenant))
+	otlpRouter.HandleFunc("/metrics", a.otlpMetrics).Methods("POST")
+
+	// Register the gRPC ingestion service.
+	ingestpb.RegisterIngestServer(a.server.GRPC, &ingestServer{app: a})
+
 	// Register the admin routes.
 	adm := NewAdmin(a)
 	adm.Register(a.server.HTTP.PathPrefix("/admin").Subrouter())
@@ -158,6 +268,9 @@ func (a *App) db(ctx context.Context) *gorm.DB {
 }
 
 func (a *App) Run() error {
+	// Start marking processes with stale heartbeats as crashed.
+	go a.runReaper(a.reaperCtx)
+
 	err := a.server.Run()
 	if err != nil {
 		level.Error(a.logger).Log("msg", "error running server", "err", err)
@@ -168,5 +281,6 @@ func (a *App) Run() error {
 }
 
 func (a *App) Shutdown() {
+	a.stopReaper()
 	a.server.Shutdown()
 }
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Methods of reducing the number of points of a panel, selected with the
+// downsample query parameter.
+const (
+	// Largest-Triangle-Three-Buckets keeps the points that most change the
+	// shape of each series, so spikes survive.
+	downsampleLTTB = "lttb"
+	// The others split the x-axis into equal buckets and aggregate the
+	// values of each series in a bucket, at the first position of the bucket.
+	downsampleMin = "min"
+	downsampleMax = "max"
+	downsampleAvg = "avg"
+)
+
+// parseDownsampleMethod returns the downsampling method of a request,
+// LTTB by default.
+func parseDownsampleMethod(method string) (string, error) {
+	switch method {
+	case "":
+		return downsampleLTTB, nil
+	case downsampleLTTB, downsampleMin, downsampleMax, downsampleAvg:
+		return method, nil
+	}
+	return "", fmt.Errorf("downsample must be %q, %q, %q or %q", downsampleLTTB, downsampleMin, downsampleMax, downsampleAvg)
+}
+
+// downsampleResponse reduces each panel of a response to about maxPoints
+// positions on its x-axis.
+func downsampleResponse(response GetModelMetricsResponse, maxPoints int, method string) {
+	for _, panels := range response.Sections {
+		for i := range panels {
+			panels[i].Series = downsampleFrame(panels[i].Series, maxPoints, method)
+		}
+	}
+}
+
+// downsampleFrame reduces a data frame to about maxPoints rows.
+//
+// With LTTB each series selects its own points, and the frame keeps the union
+// of the selected rows, so a frame of several series can have up to maxPoints
+// rows per series. NaN and infinities are not part of the triangles: the
+// first and last of each run of them are always kept, so that divergences
+// show up however few points are requested.
+func downsampleFrame(frame DataFrame, maxPoints int, method string) DataFrame {
+	if len(frame) == 0 || len(frame[0].Values) <= maxPoints {
+		return frame
+	}
+	xs := make([]float64, len(frame[0].Values))
+	for i, v := range frame[0].Values {
+		x, ok := toFloat(v)
+		if !ok {
+			// Not a numeric axis, leave it as is.
+			return frame
+		}
+		xs[i] = x
+	}
+	series := make([][]*float64, len(frame)-1)
+	for i, field := range frame[1:] {
+		series[i] = fieldNumbers(field)
+	}
+
+	if method == downsampleLTTB {
+		return selectRows(frame, series, lttbRows(xs, series, maxPoints))
+	}
+	return aggregateBuckets(frame, xs, series, maxPoints, method)
+}
+
+// lttbRows returns the rows selected by LTTB in any of the series, sorted.
+func lttbRows(xs []float64, series [][]*float64, maxPoints int) []int {
+	keep := map[int]bool{}
+	for _, values := range series {
+		var indices []int
+		var px, py []float64
+		previousFinite := true
+		for i, v := range values {
+			if v == nil {
+				continue
+			}
+			if math.IsNaN(*v) || math.IsInf(*v, 0) {
+				// Keep the first and, below, the last of each run.
+				if previousFinite {
+					keep[i] = true
+				}
+				previousFinite = false
+				continue
+			}
+			if !previousFinite {
+				keep[lastNonFinite(values, i)] = true
+			}
+			previousFinite = true
+			indices = append(indices, i)
+			px = append(px, xs[i])
+			py = append(py, *v)
+		}
+		if !previousFinite {
+			keep[lastNonFinite(values, len(values))] = true
+		}
+
+		for _, j := range lttb(px, py, maxPoints) {
+			keep[indices[j]] = true
+		}
+	}
+
+	rows := make([]int, 0, len(keep))
+	for i := range keep {
+		rows = append(rows, i)
+	}
+	sort.Ints(rows)
+	return rows
+}
+
+// lastNonFinite returns the index of the last non-null value before end,
+// which is NaN or infinite.
+func lastNonFinite(values []*float64, end int) int {
+	for i := end - 1; i >= 0; i-- {
+		if values[i] != nil {
+			return i
+		}
+	}
+	return 0
+}
+
+// lttb returns the indices of the points Largest-Triangle-Three-Buckets
+// selects, see https://skemman.is/handle/1946/15343. The first and last
+// points are always selected. Each bucket in between selects the point that
+// forms the largest triangle with the point selected in the previous bucket
+// and the average of the next one, which favours the extremes.
+func lttb(xs, ys []float64, threshold int) []int {
+	n := len(xs)
+	if threshold >= n {
+		indices := make([]int, n)
+		for i := range indices {
+			indices[i] = i
+		}
+		return indices
+	}
+	if threshold < 3 {
+		return []int{0, n - 1}
+	}
+
+	sampled := make([]int, 0, threshold)
+	sampled = append(sampled, 0)
+	every := float64(n-2) / float64(threshold-2)
+	a := 0
+	for i := 0; i < threshold-2; i++ {
+		// The average of the next bucket, the last point for the last one.
+		avgStart := int(float64(i+1)*every) + 1
+		avgEnd := min(int(float64(i+2)*every)+1, n)
+		var avgX, avgY float64
+		for j := avgStart; j < avgEnd; j++ {
+			avgX += xs[j]
+			avgY += ys[j]
+		}
+		avgX /= float64(avgEnd - avgStart)
+		avgY /= float64(avgEnd - avgStart)
+
+		rangeStart := int(float64(i)*every) + 1
+		rangeEnd := int(float64(i+1)*every) + 1
+		maxArea, next := -1.0, rangeStart
+		for j := rangeStart; j < rangeEnd; j++ {
+			area := math.Abs((xs[a]-avgX)*(ys[j]-ys[a]) - (xs[a]-xs[j])*(avgY-ys[a]))
+			if area > maxArea {
+				maxArea, next = area, j
+			}
+		}
+		sampled = append(sampled, next)
+		a = next
+	}
+	return append(sampled, n-1)
+}
+
+// selectRows returns the frame with only the given rows.
+func selectRows(frame DataFrame, series [][]*float64, rows []int) DataFrame {
+	out := make(DataFrame, len(frame))
+	out[0] = Field{Name: frame[0].Name, Type: frame[0].Type, Values: make([]interface{}, 0, len(rows))}
+	for _, i := range rows {
+		out[0].Values = append(out[0].Values, frame[0].Values[i])
+	}
+	for s, values := range series {
+		field := Field{Name: frame[s+1].Name, Type: frame[s+1].Type, Labels: frame[s+1].Labels, Values: make([]interface{}, 0, len(rows))}
+		for _, i := range rows {
+			field.appendNumber(values[i])
+		}
+		out[s+1] = field
+	}
+	return out
+}
+
+// aggregateBuckets splits the x-axis of a frame into maxPoints buckets of
+// equal width and aggregates the values of each series in each bucket. The
+// aggregates are positioned at the first x of their bucket, shared by all
+// series. Aggregates of NaN and infinities follow math.Min and math.Max, and
+// the average of a bucket holding any is NaN or infinite.
+func aggregateBuckets(frame DataFrame, xs []float64, series [][]*float64, maxPoints int, method string) DataFrame {
+	lo, hi := xs[0], xs[0]
+	for _, x := range xs {
+		lo, hi = math.Min(lo, x), math.Max(hi, x)
+	}
+	bucketOf := func(x float64) int {
+		if hi == lo {
+			return 0
+		}
+		return min(int((x-lo)/(hi-lo)*float64(maxPoints)), maxPoints-1)
+	}
+
+	// The first row of each bucket, in order of x.
+	first := make([]int, maxPoints)
+	for b := range first {
+		first[b] = -1
+	}
+	for i, x := range xs {
+		b := bucketOf(x)
+		if first[b] == -1 || x < xs[first[b]] {
+			first[b] = i
+		}
+	}
+	var buckets []int
+	for b, i := range first {
+		if i != -1 {
+			buckets = append(buckets, b)
+		}
+	}
+	position := make(map[int]int, len(buckets))
+	for p, b := range buckets {
+		position[b] = p
+	}
+
+	out := make(DataFrame, len(frame))
+	out[0] = Field{Name: frame[0].Name, Type: frame[0].Type, Values: make([]interface{}, 0, len(buckets))}
+	for _, b := range buckets {
+		out[0].Values = append(out[0].Values, frame[0].Values[first[b]])
+	}
+	for s, values := range series {
+		aggregates := make([]*float64, len(buckets))
+		counts := make([]int, len(buckets))
+		for i, v := range values {
+			if v == nil {
+				continue
+			}
+			p := position[bucketOf(xs[i])]
+			counts[p]++
+			if aggregates[p] == nil {
+				value := *v
+				aggregates[p] = &value
+				continue
+			}
+			switch method {
+			case downsampleMin:
+				*aggregates[p] = math.Min(*aggregates[p], *v)
+			case downsampleMax:
+				*aggregates[p] = math.Max(*aggregates[p], *v)
+			case downsampleAvg:
+				*aggregates[p] += *v
+			}
+		}
+		field := Field{Name: frame[s+1].Name, Type: frame[s+1].Type, Labels: frame[s+1].Labels, Values: make([]interface{}, 0, len(buckets))}
+		for p, aggregate := range aggregates {
+			if aggregate != nil && method == downsampleAvg {
+				*aggregate /= float64(counts[p])
+			}
+			field.appendNumber(aggregate)
+		}
+		out[s+1] = field
+	}
+	return out
+}
+
+// fieldNumbers returns the values of a number field, with NaN and infinities
+// restored from its entities.
+func fieldNumbers(field Field) []*float64 {
+	values := make([]*float64, len(field.Values))
+	for i, v := range field.Values {
+		if f, ok := toFloat(v); ok {
+			values[i] = &f
+		}
+	}
+	if field.Entities != nil {
+		for _, entity := range []struct {
+			indices []int
+			value   float64
+		}{
+			{field.Entities.NaN, math.NaN()},
+			{field.Entities.Inf, math.Inf(1)},
+			{field.Entities.NegInf, math.Inf(-1)},
+		} {
+			for _, i := range entity.indices {
+				value := entity.value
+				values[i] = &value
+			}
+		}
+	}
+	return values
+}
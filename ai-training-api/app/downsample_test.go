@@ -0,0 +1,111 @@
+package api
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lossFrame returns a frame of a slowly decreasing loss over n steps, with
+// the given values overridden.
+func lossFrame(n int, overrides map[int]float64) DataFrame {
+	steps := Field{Name: "step", Type: "number"}
+	loss := Field{Name: "process", Type: "number"}
+	for i := 0; i < n; i++ {
+		steps.Values = append(steps.Values, uint32(i+1))
+		v := 1 / float64(i+1)
+		if o, ok := overrides[i]; ok {
+			v = o
+		}
+		loss.appendNumber(&v)
+	}
+	return DataFrame{steps, loss}
+}
+
+func TestDownsampleFrameKeepsSpikes(t *testing.T) {
+	frame := lossFrame(100000, map[int]float64{31337: 50, 77777: -3})
+
+	for _, method := range []string{downsampleLTTB, downsampleMax} {
+		t.Run(method, func(t *testing.T) {
+			downsampled := downsampleFrame(frame, 500, method)
+			require.Len(t, downsampled, 2)
+			assert.LessOrEqual(t, len(downsampled[0].Values), 500)
+			assert.Equal(t, len(downsampled[0].Values), len(downsampled[1].Values))
+			assert.Contains(t, downsampled[1].Values, float64(50))
+			// The first and last steps are kept.
+			assert.Equal(t, uint32(1), downsampled[0].Values[0])
+			if method == downsampleLTTB {
+				assert.Contains(t, downsampled[1].Values, float64(-3))
+				assert.Equal(t, uint32(100000), downsampled[0].Values[len(downsampled[0].Values)-1])
+			}
+		})
+	}
+
+	downsampled := downsampleFrame(frame, 500, downsampleMin)
+	assert.Contains(t, downsampled[1].Values, float64(-3))
+	assert.NotContains(t, downsampled[1].Values, float64(50))
+}
+
+func TestDownsampleFrameKeepsNonFiniteRuns(t *testing.T) {
+	overrides := map[int]float64{500: math.NaN()}
+	// The loss diverges to infinity for the last steps.
+	for i := 9000; i < 10000; i++ {
+		overrides[i] = math.Inf(1)
+	}
+	frame := lossFrame(10000, overrides)
+
+	downsampled := downsampleFrame(frame, 10, downsampleLTTB)
+	entities := downsampled[1].Entities
+	require.NotNil(t, entities)
+	require.Len(t, entities.NaN, 1)
+	assert.Equal(t, uint32(501), downsampled[0].Values[entities.NaN[0]])
+	require.Len(t, entities.Inf, 2)
+	assert.Equal(t, uint32(9001), downsampled[0].Values[entities.Inf[0]])
+	assert.Equal(t, uint32(10000), downsampled[0].Values[entities.Inf[1]])
+
+	downsampled = downsampleFrame(frame, 10, downsampleAvg)
+	assert.Len(t, downsampled[0].Values, 10)
+	require.NotNil(t, downsampled[1].Entities)
+	assert.Len(t, downsampled[1].Entities.NaN, 1)
+	assert.Len(t, downsampled[1].Entities.Inf, 1)
+}
+
+func TestDownsampleFrameAggregatesBuckets(t *testing.T) {
+	one, two, four := 1.0, 2.0, 4.0
+	frame := DataFrame{
+		{Name: "step", Type: "number", Values: []interface{}{uint32(1), uint32(2), uint32(3), uint32(4)}},
+		{Name: "a", Type: "number"},
+		{Name: "b", Type: "number"},
+	}
+	for _, v := range []*float64{&one, &two, &four, &one} {
+		frame[1].appendNumber(v)
+	}
+	for _, v := range []*float64{nil, nil, &two, &four} {
+		frame[2].appendNumber(v)
+	}
+
+	tests := map[string][2][]interface{}{
+		downsampleMin: {{float64(1), float64(1)}, {nil, float64(2)}},
+		downsampleMax: {{float64(2), float64(4)}, {nil, float64(4)}},
+		downsampleAvg: {{1.5, 2.5}, {nil, float64(3)}},
+	}
+	for method, want := range tests {
+		downsampled := downsampleFrame(frame, 2, method)
+		assert.Equal(t, []interface{}{uint32(1), uint32(3)}, downsampled[0].Values, method)
+		assert.Equal(t, want[0], downsampled[1].Values, method)
+		assert.Equal(t, want[1], downsampled[2].Values, method)
+	}
+
+	// Frames with few enough points are left as is.
+	assert.Equal(t, frame, downsampleFrame(frame, 4, downsampleLTTB))
+}
+
+func TestLTTB(t *testing.T) {
+	xs := []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	ys := []float64{0, 0, 0, 10, 0, 0, 0, 0, -10, 0}
+	assert.Equal(t, []int{0, 3, 8, 9}, lttb(xs, ys, 4))
+	assert.Equal(t, []int{0, 9}, lttb(xs, ys, 2))
+	assert.Len(t, lttb(xs, ys, 20), 10)
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+)
+
+// Per-process results of changing the members of a group.
+const (
+	GroupMemberMoved         = "moved"
+	GroupMemberRemoved       = "removed"
+	GroupMemberAlreadyMember = "already_member"
+	GroupMemberNotMember     = "not_member"
+	GroupMemberNotFound      = "not_found"
+)
+
+type groupMembersRequest struct {
+	ProcessIDs []uuid.UUID `json:"process_ids"`
+}
+
+// GroupMemberResult is the outcome of adding a process to or removing it
+// from a group.
+type GroupMemberResult struct {
+	ProcessID uuid.UUID `json:"process_id"`
+	Result    string    `json:"result"`
+}
+
+type groupMembersResponse struct {
+	Group   model.Group         `json:"group"`
+	Results []GroupMemberResult `json:"results"`
+}
+
+// addGroupMembers moves processes of the tenant into a group.
+func (a *App) addGroupMembers(tenantID string, req *http.Request) (interface{}, error) {
+	return a.changeGroupMembers(tenantID, req, addProcessesToGroup)
+}
+
+// removeGroupMembers removes processes from a group, leaving them without a
+// group.
+func (a *App) removeGroupMembers(tenantID string, req *http.Request) (interface{}, error) {
+	return a.changeGroupMembers(tenantID, req, removeProcessesFromGroup)
+}
+
+type groupMembersFunc func(tx *gorm.DB, tenantID string, groupID uuid.UUID, processIDs []uuid.UUID) ([]GroupMemberResult, error)
+
+func (a *App) changeGroupMembers(tenantID string, req *http.Request, change groupMembersFunc) (interface{}, error) {
+	groupId := namedParam(req, "id")
+	parsed, err := uuid.Parse(groupId)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	// Read and parse request body.
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+	defer req.Body.Close()
+	var data groupMembersRequest
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	var resp groupMembersResponse
+	err = a.db(req.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Where(&model.Group{
+				TenantID: tenantID,
+				ID:       parsed,
+			}).First(&model.Group{}).Error
+		if err != nil {
+			return middleware.ErrNotFound(err)
+		}
+
+		resp.Results, err = change(tx, tenantID, parsed, data.ProcessIDs)
+		if err != nil {
+			return err
+		}
+		return tx.Preload("Processes", "tenant_id = ?", tenantID).First(&resp.Group, "id = ?", parsed).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	level.Info(a.logger).Log("msg", "changed group members", "tenantID", tenantID, "group_id", groupId, "processes", len(data.ProcessIDs))
+	return resp, nil
+}
+
+// findTenantProcesses returns the processes of the tenant with the given
+// IDs, by ID. Processes of other tenants are never returned.
+func findTenantProcesses(tx *gorm.DB, tenantID string, processIDs []uuid.UUID) (map[uuid.UUID]model.Process, error) {
+	var processes []model.Process
+	err := tx.
+		Where("tenant_id = ? AND id IN ?", tenantID, processIDs).
+		Find(&processes).Error
+	if err != nil {
+		return nil, fmt.Errorf("error finding processes: %w", err)
+	}
+	byID := make(map[uuid.UUID]model.Process, len(processes))
+	for _, p := range processes {
+		byID[p.ID] = p
+	}
+	return byID, nil
+}
+
+// addProcessesToGroup moves the tenant's processes into a group, then
+// recomputes the times of the group and of the groups they left.
+func addProcessesToGroup(tx *gorm.DB, tenantID string, groupID uuid.UUID, processIDs []uuid.UUID) ([]GroupMemberResult, error) {
+	processes, err := findTenantProcesses(tx, tenantID, processIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]GroupMemberResult, 0, len(processIDs))
+	var moved []uuid.UUID
+	affected := map[uuid.UUID]bool{groupID: true}
+	seen := map[uuid.UUID]bool{}
+	for _, id := range processIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		p, ok := processes[id]
+		switch {
+		case !ok:
+			results = append(results, GroupMemberResult{ProcessID: id, Result: GroupMemberNotFound})
+		case p.GroupID != nil && *p.GroupID == groupID:
+			results = append(results, GroupMemberResult{ProcessID: id, Result: GroupMemberAlreadyMember})
+		default:
+			results = append(results, GroupMemberResult{ProcessID: id, Result: GroupMemberMoved})
+			moved = append(moved, id)
+			if p.GroupID != nil {
+				affected[*p.GroupID] = true
+			}
+		}
+	}
+
+	if len(moved) > 0 {
+		err = tx.Model(&model.Process{}).
+			Where("tenant_id = ? AND id IN ?", tenantID, moved).
+			Update("group_id", groupID).Error
+		if err != nil {
+			return nil, fmt.Errorf("error adding processes to group: %w", err)
+		}
+	}
+
+	for id := range affected {
+		err = updateGroupTimes(tx, tenantID, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// removeProcessesFromGroup removes the tenant's processes from a group and
+// recomputes its times.
+func removeProcessesFromGroup(tx *gorm.DB, tenantID string, groupID uuid.UUID, processIDs []uuid.UUID) ([]GroupMemberResult, error) {
+	processes, err := findTenantProcesses(tx, tenantID, processIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]GroupMemberResult, 0, len(processIDs))
+	var removed []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, id := range processIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		p, ok := processes[id]
+		switch {
+		case !ok:
+			results = append(results, GroupMemberResult{ProcessID: id, Result: GroupMemberNotFound})
+		case p.GroupID == nil || *p.GroupID != groupID:
+			results = append(results, GroupMemberResult{ProcessID: id, Result: GroupMemberNotMember})
+		default:
+			results = append(results, GroupMemberResult{ProcessID: id, Result: GroupMemberRemoved})
+			removed = append(removed, id)
+		}
+	}
+
+	if len(removed) > 0 {
+		err = tx.Model(&model.Process{}).
+			Where("tenant_id = ? AND group_id = ? AND id IN ?", tenantID, groupID, removed).
+			Update("group_id", nil).Error
+		if err != nil {
+			return nil, fmt.Errorf("error removing processes from group: %w", err)
+		}
+	}
+
+	err = updateGroupTimes(tx, tenantID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// updateGroupTimes recomputes the start and end time of a group from its
+// processes. Groups of other tenants are left alone.
+func updateGroupTimes(tx *gorm.DB, tenantID string, groupID uuid.UUID) error {
+	group := model.Group{}
+	err := tx.
+		Preload("Processes", "tenant_id = ?", tenantID).
+		Where(&model.Group{
+			TenantID: tenantID,
+			ID:       groupID,
+		}).First(&group).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error finding group: %w", err)
+	}
+
+	group.UpdateTimes(group.Processes)
+	err = tx.Model(&model.Group{}).
+		Where("id = ?", groupID).
+		Updates(map[string]interface{}{
+			"start_time": group.StartTime,
+			"end_time":   group.EndTime,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("error updating group times: %w", err)
+	}
+	return nil
+}
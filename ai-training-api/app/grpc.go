@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/grafana/dskit/user"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/ingestpb"
+	"github.com/grafana/ai-training-o11y/ai-training-api/jsonpatch"
+	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+)
+
+// ingestServer serves the gRPC ingestion API. It shares the implementation
+// of the HTTP endpoints, so both behave the same.
+type ingestServer struct {
+	ingestpb.UnimplementedIngestServer
+	app *App
+}
+
+func (s *ingestServer) RegisterProcess(ctx context.Context, req *ingestpb.RegisterProcessRequest) (*ingestpb.Process, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := structToMetadata(req.GetUserMetadata())
+	if err != nil {
+		return nil, middleware.GRPCError(err)
+	}
+
+	process, err := s.app.createProcess(ctx, tenantID, req.GetProject(), req.GetGroup(), metadata)
+	if err != nil {
+		return nil, middleware.GRPCError(err)
+	}
+	return processToProto(*process), nil
+}
+
+func (s *ingestServer) UpdateProcessState(ctx context.Context, req *ingestpb.UpdateProcessStateRequest) (*ingestpb.Process, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	processID, err := parseProcessID(req.GetProcessId())
+	if err != nil {
+		return nil, middleware.GRPCError(err)
+	}
+
+	process, err := s.app.transitionProcess(ctx, tenantID, processID, updateProcessStateRequest{
+		State:   req.GetState(),
+		Reason:  req.GetReason(),
+		Message: req.GetMessage(),
+	})
+	if err != nil {
+		return nil, middleware.GRPCError(err)
+	}
+
+	level.Info(s.app.logger).Log("msg", "updated process state", "tenantID", tenantID, "process_id", processID, "state", process.Status)
+	return processToProto(process), nil
+}
+
+func (s *ingestServer) UpdateProcessMetadata(ctx context.Context, req *ingestpb.UpdateProcessMetadataRequest) (*ingestpb.Process, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	processID, err := parseProcessID(req.GetProcessId())
+	if err != nil {
+		return nil, middleware.GRPCError(err)
+	}
+
+	var patch func(doc interface{}) (interface{}, error)
+	if req.GetUserMetadata() != nil {
+		mergePatch, err := structToMetadata(req.GetUserMetadata())
+		if err != nil {
+			return nil, middleware.GRPCError(err)
+		}
+		patch = func(doc interface{}) (interface{}, error) {
+			return jsonpatch.MergePatch(doc, mergePatch), nil
+		}
+	}
+	source := req.GetSource()
+	if source == "" {
+		source = model.MetadataSourceUpdate
+	}
+
+	err = s.app.patchProcessMetadata(ctx, tenantID, processID, patch, source)
+	if err != nil {
+		return nil, middleware.GRPCError(err)
+	}
+
+	level.Info(s.app.logger).Log("msg", "updated metadata", "tenantID", tenantID, "process_id", processID)
+
+	process, err := s.app.findProcess(ctx, tenantID, processID)
+	if err != nil {
+		return nil, middleware.GRPCError(err)
+	}
+	return processToProto(process), nil
+}
+
+// SendMetricPoints saves the points of a stream in chunks as they are
+// received, like NDJSON requests to the HTTP endpoint. Unlike those, an
+// invalid point fails the stream, since clients of a typed API have no
+// excuse for sending one.
+func (s *ingestServer) SendMetricPoints(stream ingestpb.Ingest_SendMetricPointsServer) error {
+	ctx := stream.Context()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	defer func() {
+		s.app.ingestDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	var (
+		ingest *chunkedIngest
+		point  int
+	)
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// The first message sets the process and the conflict policy.
+		if ingest == nil {
+			ingest, err = s.newChunkedIngest(ctx, tenantID, req)
+			if err != nil {
+				return middleware.GRPCError(err)
+			}
+		}
+
+		receivedAt := time.Now()
+		for _, p := range req.GetPoints() {
+			point++
+			metrics, err := modelMetricsFromPayload(metricPointToPayload(p), receivedAt)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "point %d: %v", point, err)
+			}
+			if err := ingest.add(metrics); err != nil {
+				return middleware.GRPCError(err)
+			}
+		}
+	}
+	if ingest == nil {
+		return status.Error(codes.InvalidArgument, "no process ID sent")
+	}
+	if err := ingest.finish(); err != nil {
+		return middleware.GRPCError(err)
+	}
+
+	return stream.SendAndClose(&ingestpb.SendMetricPointsResponse{
+		MetricsCreated:     uint64(ingest.total.Created),
+		MetricsSkipped:     uint64(ingest.total.Skipped),
+		MetricsOverwritten: uint64(ingest.total.Overwritten),
+	})
+}
+
+func (s *ingestServer) newChunkedIngest(ctx context.Context, tenantID string, req *ingestpb.SendMetricPointsRequest) (*chunkedIngest, error) {
+	processID, err := parseProcessID(req.GetProcessId())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.app.findProcess(ctx, tenantID, processID); err != nil {
+		return nil, err
+	}
+
+	var policy string
+	switch req.GetOnConflict() {
+	case ingestpb.ConflictPolicy_CONFLICT_POLICY_ERROR:
+		policy = conflictError
+	case ingestpb.ConflictPolicy_CONFLICT_POLICY_IGNORE:
+		policy = conflictIgnore
+	case ingestpb.ConflictPolicy_CONFLICT_POLICY_OVERWRITE:
+		policy = conflictOverwrite
+	default:
+		return nil, middleware.ErrBadRequest(fmt.Errorf("unknown conflict policy %v", req.GetOnConflict()))
+	}
+
+	return &chunkedIngest{app: s.app, ctx: ctx, tenantID: tenantID, processID: processID, policy: policy}, nil
+}
+
+// tenantFromContext returns the tenant the authn interceptors injected.
+func tenantFromContext(ctx context.Context) (string, error) {
+	tenantID, err := user.ExtractOrgID(ctx)
+	if err != nil {
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	}
+	return tenantID, nil
+}
+
+func parseProcessID(s string) (uuid.UUID, error) {
+	if s == "" {
+		return uuid.Nil, middleware.ErrBadRequest(fmt.Errorf("process ID is empty"))
+	}
+	processID, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, middleware.ErrBadRequest(fmt.Errorf("invalid process ID: %w", err))
+	}
+	return processID, nil
+}
+
+// structToMetadata converts metadata sent as a Struct to what decoding it
+// from a JSON body would give, with numbers as json.Number.
+func structToMetadata(s *structpb.Struct) (map[string]interface{}, error) {
+	if s == nil {
+		return nil, nil
+	}
+	body, err := protojson.Marshal(s)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(fmt.Errorf("invalid user_metadata: %w", err))
+	}
+	metadata := map[string]interface{}{}
+	if err := decodeJSONWithNumbers(body, &metadata); err != nil {
+		return nil, middleware.ErrBadRequest(fmt.Errorf("invalid user_metadata: %w", err))
+	}
+	return metadata, nil
+}
+
+func metricPointToPayload(p *ingestpb.MetricPoint) AddModelMetricsPayload {
+	payload := AddModelMetricsPayload{
+		StepName:  p.GetStepName(),
+		StepValue: p.GetStepValue(),
+		Metrics:   make(map[string]MetricValue, len(p.GetMetrics())),
+		Axes:      p.GetAxes(),
+	}
+	for name, value := range p.GetMetrics() {
+		payload.Metrics[name] = MetricValue(value)
+	}
+	if p.GetTimestamp() != nil {
+		timestamp := MetricTimestamp(p.GetTimestamp().AsTime())
+		payload.Timestamp = &timestamp
+	}
+	return payload
+}
+
+func processToProto(p model.Process) *ingestpb.Process {
+	process := &ingestpb.Process{
+		Id:        p.ID.String(),
+		Project:   p.Project,
+		Status:    p.Status,
+		StartTime: timestamppb.New(p.StartTime),
+	}
+	if p.GroupID != nil {
+		process.GroupId = p.GroupID.String()
+	}
+	if p.EndTime.Valid {
+		process.EndTime = timestamppb.New(p.EndTime.Time)
+	}
+	return process
+}
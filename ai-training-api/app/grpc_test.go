@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/ingestpb"
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+)
+
+func TestAppIngestsOverGRPC(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	conn, err := grpc.Dial(testApp.server.GRPCListenAddr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := ingestpb.NewIngestClient(conn)
+	ctx := context.Background()
+
+	metadata, err := structpb.NewStruct(map[string]interface{}{"key1": "value1", "key2": 2})
+	require.NoError(t, err)
+	process, err := client.RegisterProcess(ctx, &ingestpb.RegisterProcessRequest{
+		Project:      "project",
+		Group:        "group1",
+		UserMetadata: metadata,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, process.Id)
+	assert.NotEmpty(t, process.GroupId)
+	assert.Equal(t, model.ProcessStatusRunning, process.Status)
+
+	patch, err := structpb.NewStruct(map[string]interface{}{"key1": nil, "key3": "value3"})
+	require.NoError(t, err)
+	_, err = client.UpdateProcessMetadata(ctx, &ingestpb.UpdateProcessMetadataRequest{
+		ProcessId:    process.Id,
+		UserMetadata: patch,
+	})
+	require.NoError(t, err)
+
+	// Points are sent over several messages, the first one setting the
+	// process.
+	stream, err := client.SendMetricPoints(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&ingestpb.SendMetricPointsRequest{
+		ProcessId: process.Id,
+		Points: []*ingestpb.MetricPoint{
+			{StepName: "step", StepValue: 1, Metrics: map[string]float64{"loss": 2, "accuracy": 0.5}},
+		},
+	}))
+	require.NoError(t, stream.Send(&ingestpb.SendMetricPointsRequest{
+		Points: []*ingestpb.MetricPoint{
+			{StepName: "step", StepValue: 2, Metrics: map[string]float64{"loss": math.Inf(1)}, Axes: map[string]float64{"epoch": 1}},
+		},
+	}))
+	summary, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), summary.MetricsCreated)
+
+	// Points that were already logged are skipped with the ignore policy.
+	stream, err = client.SendMetricPoints(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&ingestpb.SendMetricPointsRequest{
+		ProcessId:  process.Id,
+		OnConflict: ingestpb.ConflictPolicy_CONFLICT_POLICY_IGNORE,
+		Points: []*ingestpb.MetricPoint{
+			{StepName: "step", StepValue: 2, Metrics: map[string]float64{"loss": 1}},
+			{StepName: "step", StepValue: 3, Metrics: map[string]float64{"loss": 1}},
+		},
+	}))
+	summary, err = stream.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), summary.MetricsCreated)
+	assert.Equal(t, uint64(1), summary.MetricsSkipped)
+
+	// Invalid points fail the stream.
+	stream, err = client.SendMetricPoints(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&ingestpb.SendMetricPointsRequest{
+		ProcessId: process.Id,
+		Points:    []*ingestpb.MetricPoint{{StepName: "step", StepValue: 0, Metrics: map[string]float64{"loss": 1}}},
+	}))
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.UpdateProcessState(ctx, &ingestpb.UpdateProcessStateRequest{ProcessId: "4e6ab3a4-ef6d-4b5f-a9e2-d0a0b0d0c0e0", State: model.ProcessStatusSucceeded})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	finished, err := client.UpdateProcessState(ctx, &ingestpb.UpdateProcessStateRequest{ProcessId: process.Id, State: model.ProcessStatusSucceeded, Reason: "done"})
+	require.NoError(t, err)
+	assert.Equal(t, model.ProcessStatusSucceeded, finished.Status)
+	assert.NotNil(t, finished.EndTime)
+
+	// What was sent over gRPC is served over HTTP.
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Get(baseURL + "/process/" + process.Id)
+	require.NoError(t, err)
+	gpr := read[getProcessResponse](t, resp)
+	assert.Equal(t, model.ProcessStatusSucceeded, gpr.Data.Status)
+	assert.Equal(t, "done", gpr.Data.ExitReason)
+	assert.Equal(t, map[string]interface{}{"key2": float64(2), "key3": "value3"}, gpr.Data.Metadata)
+
+	resp, err = httpC.Post(baseURL+"/processes/model-metrics", "application/json", bytes.NewBufferString(`["`+process.Id+`"]`))
+	require.NoError(t, err)
+	gmr := read[getModelMetricsResponse](t, resp)
+	panels := map[string]int{}
+	for _, panel := range gmr.Data.Sections["default"] {
+		panels[panel.Title] = len(panel.Series[0].Values)
+	}
+	assert.Equal(t, map[string]int{"loss": 3, "accuracy": 1}, panels)
+}
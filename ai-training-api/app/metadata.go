@@ -0,0 +1,502 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+)
+
+const (
+	defaultMetadataTopValues = 10
+	maxMetadataTopValues     = 100
+	defaultMetadataBuckets   = 10
+	maxMetadataBuckets       = 100
+)
+
+// MetadataKeyStats describes a metadata key and the values stored under it.
+type MetadataKeyStats struct {
+	Key string `json:"key"`
+	// Number of processes carrying the key.
+	ProcessCount int `json:"process_count"`
+	// Number of processes per observed value type.
+	Types map[string]int `json:"types"`
+	// The most common values, most common first.
+	TopValues []MetadataValueCount `json:"top_values"`
+	// Distribution of int and float values, if there are any.
+	Numeric *NumericStats `json:"numeric,omitempty"`
+}
+
+type MetadataValueCount struct {
+	Value interface{} `json:"value"`
+	Type  string      `json:"type"`
+	Count int         `json:"count"`
+}
+
+type NumericStats struct {
+	Min       float64           `json:"min"`
+	Max       float64           `json:"max"`
+	Histogram []HistogramBucket `json:"histogram"`
+}
+
+// HistogramBucket counts the values in [Min, Max). The last bucket also
+// includes its Max.
+type HistogramBucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// metadataValueRow is a distinct metadata value and the number of processes
+// carrying it.
+type metadataValueRow struct {
+	Key   string
+	Type  string
+	Value []byte
+	Count int
+}
+
+// getMetadataKeys returns every metadata key of the tenant, optionally limited
+// to a project and a key prefix, with the distribution of its values.
+func (a *App) getMetadataKeys(tenantID string, req *http.Request) (interface{}, error) {
+	query := req.URL.Query()
+	top, err := intParam(query.Get("top"), defaultMetadataTopValues, 0, maxMetadataTopValues)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(fmt.Errorf("invalid top: %w", err))
+	}
+	buckets, err := intParam(query.Get("buckets"), defaultMetadataBuckets, 1, maxMetadataBuckets)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(fmt.Errorf("invalid buckets: %w", err))
+	}
+
+	q := a.db(req.Context()).
+		Table("metadata_kvs").
+		Where("metadata_kvs.tenant_id = ?", tenantID)
+	if project := query.Get("project"); project != "" {
+		q = q.Joins("JOIN processes ON processes.id = metadata_kvs.process_id").
+			Where("processes.tenant_id = ? AND processes.project = ?", tenantID, project)
+	}
+	if prefix := query.Get("prefix"); prefix != "" {
+		q = q.Where("metadata_kvs.`key` LIKE ? ESCAPE '!'", escapeLike(prefix)+"%")
+	}
+
+	var rows []metadataValueRow
+	err = q.
+		Select("metadata_kvs.`key` AS `key`, metadata_kvs.type AS type, metadata_kvs.value AS value, COUNT(DISTINCT metadata_kvs.process_id) AS count").
+		Group("metadata_kvs.`key`, metadata_kvs.type, metadata_kvs.value").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating metadata: %w", err)
+	}
+
+	stats := metadataKeyStats(rows, top, buckets)
+
+	level.Info(a.logger).Log("msg", "found metadata keys", "tenantID", tenantID, "keys", len(stats))
+	return stats, nil
+}
+
+// metadataKeyStats aggregates distinct metadata values into per-key stats,
+// sorted by key.
+func metadataKeyStats(rows []metadataValueRow, top, buckets int) []MetadataKeyStats {
+	byKey := map[string]*MetadataKeyStats{}
+	numbers := map[string][]weightedValue{}
+	for _, row := range rows {
+		s, ok := byKey[row.Key]
+		if !ok {
+			s = &MetadataKeyStats{Key: row.Key, Types: map[string]int{}, TopValues: []MetadataValueCount{}}
+			byKey[row.Key] = s
+		}
+		s.ProcessCount += row.Count
+		s.Types[row.Type] += row.Count
+
+		value, err := model.UnmarshalMetadataValue(row.Value, row.Type)
+		if err != nil {
+			// Values we cannot decode still count towards the key, but
+			// cannot be shown.
+			continue
+		}
+		s.TopValues = append(s.TopValues, MetadataValueCount{Value: value, Type: row.Type, Count: row.Count})
+
+		if f, ok := toFloat(value); ok {
+			numbers[row.Key] = append(numbers[row.Key], weightedValue{value: f, count: row.Count})
+		}
+	}
+
+	stats := make([]MetadataKeyStats, 0, len(byKey))
+	for key, s := range byKey {
+		slices.SortStableFunc(s.TopValues, func(a, b MetadataValueCount) int {
+			if a.Count != b.Count {
+				return b.Count - a.Count
+			}
+			return strings.Compare(fmt.Sprint(a.Value), fmt.Sprint(b.Value))
+		})
+		if len(s.TopValues) > top {
+			s.TopValues = s.TopValues[:top]
+		}
+		if values, ok := numbers[key]; ok {
+			s.Numeric = numericStats(values, buckets)
+		}
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b MetadataKeyStats) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return stats
+}
+
+type weightedValue struct {
+	value float64
+	count int
+}
+
+// numericStats computes the range of the values and an equal width histogram
+// over it.
+func numericStats(values []weightedValue, buckets int) *NumericStats {
+	s := &NumericStats{Min: math.Inf(1), Max: math.Inf(-1)}
+	for _, v := range values {
+		s.Min = math.Min(s.Min, v.value)
+		s.Max = math.Max(s.Max, v.value)
+	}
+
+	// All values are equal, a single bucket holds them all.
+	if s.Min == s.Max {
+		buckets = 1
+	}
+	width := (s.Max - s.Min) / float64(buckets)
+	s.Histogram = make([]HistogramBucket, buckets)
+	for i := range s.Histogram {
+		s.Histogram[i].Min = s.Min + float64(i)*width
+		s.Histogram[i].Max = s.Min + float64(i+1)*width
+	}
+	s.Histogram[buckets-1].Max = s.Max
+
+	for _, v := range values {
+		i := buckets - 1
+		if width > 0 {
+			i = min(int((v.value-s.Min)/width), buckets-1)
+		}
+		s.Histogram[i].Count += v.count
+	}
+	return s
+}
+
+// toFloat returns the value of a number, as decoded from metadata or stored
+// in the fields of a data frame. JSON numbers out of the range of a float64
+// are not numbers.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil && !math.IsInf(f, 0)
+	}
+	return 0, false
+}
+
+// intParam parses an optional integer query parameter within [min, max].
+func intParam(s string, def, min, max int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("must be between %d and %d", min, max)
+	}
+	return v, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern using '!' as the escape
+// character, which unlike '\' means the same in SQLite and MySQL.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(s)
+}
+
+// writeMetadata stores the flattened metadata of a process, replacing the
+// keys that already exist, and records every write in the metadata history.
+// Keys rewritten with the value they already have are left alone, so that
+// the history only has the changes.
+func writeMetadata(tx *gorm.DB, tenantID string, processID uuid.UUID, flat map[string]interface{}, source string, now time.Time) error {
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	var existing []model.MetadataKV
+	err := tx.
+		Where(&model.MetadataKV{
+			TenantID:  tenantID,
+			ProcessID: processID,
+		}).
+		Where("`key` IN ?", keys).
+		Find(&existing).Error
+	if err != nil {
+		return fmt.Errorf("error finding metadata: %w", err)
+	}
+	current := make(map[string]model.MetadataKV, len(existing))
+	for _, kv := range existing {
+		current[kv.Key] = kv
+	}
+
+	for key, value := range flat {
+		kv := model.NewMetadataKV(tenantID, processID, key, value)
+		if old, ok := current[key]; ok && old.Type == kv.Type && bytes.Equal(old.Value, kv.Value) {
+			continue
+		}
+		err := tx.
+			Where(&model.MetadataKV{
+				TenantID:  tenantID,
+				Key:       key,
+				ProcessID: processID,
+			}).Delete(&model.MetadataKV{}).Error
+		if err != nil {
+			return fmt.Errorf("error replacing metadata %q: %w", key, err)
+		}
+		err = tx.Create(&kv).Error
+		if err != nil {
+			return fmt.Errorf("error creating metadata %q: %w", key, err)
+		}
+
+		history := model.NewMetadataHistory(kv, source, now)
+		err = tx.Create(&history).Error
+		if err != nil {
+			return fmt.Errorf("error recording metadata history %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// MetadataVersion is a single write of a metadata key.
+type MetadataVersion struct {
+	Version uint64      `json:"version"`
+	Key     string      `json:"key"`
+	Value   interface{} `json:"value"`
+	Type    string      `json:"type"`
+	Deleted bool        `json:"deleted"`
+	Source  string      `json:"source"`
+	Time    time.Time   `json:"time"`
+}
+
+// getMetadataHistory returns the writes of a process's metadata, oldest
+// first, optionally limited to a single key.
+func (a *App) getMetadataHistory(tenantID string, req *http.Request) (interface{}, error) {
+	processID := namedParam(req, "id")
+	parsed, err := uuid.Parse(processID)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	if _, err := a.findProcess(req.Context(), tenantID, parsed); err != nil {
+		return nil, err
+	}
+
+	q := a.db(req.Context()).
+		Where(&model.MetadataHistory{
+			TenantID:  tenantID,
+			ProcessID: parsed,
+		})
+	if key := req.URL.Query().Get("key"); key != "" {
+		q = q.Where("`key` = ?", key)
+	}
+	var history []model.MetadataHistory
+	err = q.Order("version").Find(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("error finding metadata history: %w", err)
+	}
+
+	versions := make([]MetadataVersion, 0, len(history))
+	for _, h := range history {
+		v := MetadataVersion{
+			Version: h.Version,
+			Key:     h.Key,
+			Type:    h.Type,
+			Deleted: h.Deleted,
+			Source:  h.Source,
+			Time:    h.Time,
+		}
+		if !h.Deleted {
+			v.Value, err = model.UnmarshalMetadataValue(h.Value, h.Type)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding metadata %q: %w", h.Key, err)
+			}
+		}
+		versions = append(versions, v)
+	}
+
+	level.Info(a.logger).Log("msg", "found metadata history", "tenantID", tenantID, "process_id", processID, "versions", len(versions))
+	return versions, nil
+}
+
+// metadataAsOf returns the metadata of a process as it was at the given time.
+func (a *App) metadataAsOf(ctx context.Context, tenantID string, processID uuid.UUID, asOf time.Time) ([]model.MetadataKV, error) {
+	var history []model.MetadataHistory
+	err := a.db(ctx).
+		Where(&model.MetadataHistory{
+			TenantID:  tenantID,
+			ProcessID: processID,
+		}).
+		Where("time <= ?", asOf.Local()).
+		Order("version").
+		Find(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("error finding metadata history: %w", err)
+	}
+	return model.MetadataAsOf(history), nil
+}
+
+// deleteMetadata removes metadata keys of a process and records their
+// deletion in the metadata history.
+func deleteMetadata(tx *gorm.DB, tenantID string, processID uuid.UUID, keys []string, source string, now time.Time) error {
+	for _, key := range keys {
+		err := tx.
+			Where(&model.MetadataKV{
+				TenantID:  tenantID,
+				Key:       key,
+				ProcessID: processID,
+			}).Delete(&model.MetadataKV{}).Error
+		if err != nil {
+			return fmt.Errorf("error deleting metadata %q: %w", key, err)
+		}
+
+		history := model.MetadataHistory{
+			TenantID:  tenantID,
+			ProcessID: processID,
+			Key:       key,
+			Deleted:   true,
+			Source:    source,
+			Time:      now,
+		}
+		err = tx.Create(&history).Error
+		if err != nil {
+			return fmt.Errorf("error recording metadata history %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// patchMetadata applies patch to the metadata document of a process, then
+// writes the flattened keys that changed and deletes the ones that are gone.
+// It should run in a transaction so that a failing patch leaves the metadata
+// untouched.
+func patchMetadata(tx *gorm.DB, tenantID string, processID uuid.UUID, patch func(doc interface{}) (interface{}, error), source string, now time.Time) error {
+	var rows []model.MetadataKV
+	err := tx.
+		Where(&model.MetadataKV{
+			TenantID:  tenantID,
+			ProcessID: processID,
+		}).Find(&rows).Error
+	if err != nil {
+		return fmt.Errorf("error finding metadata: %w", err)
+	}
+
+	nested, err := model.NestMetadata(rows)
+	if err != nil {
+		return err
+	}
+	// Round-trip through JSON so that the document holds the same types as
+	// a decoded patch.
+	b, err := json.Marshal(nested)
+	if err != nil {
+		return fmt.Errorf("error encoding metadata: %w", err)
+	}
+	var doc map[string]interface{}
+	err = decodeJSONWithNumbers(b, &doc)
+	if err != nil {
+		return fmt.Errorf("error decoding metadata: %w", err)
+	}
+	before := model.FlattenMetadata(doc)
+
+	patched, err := patch(doc)
+	if err != nil {
+		return err
+	}
+	patchedDoc, ok := patched.(map[string]interface{})
+	if !ok {
+		return middleware.ErrBadRequest(fmt.Errorf("metadata must be an object"))
+	}
+	after := model.FlattenMetadata(patchedDoc)
+
+	changed := map[string]interface{}{}
+	for key, value := range after {
+		old, ok := before[key]
+		if !ok || !sameMetadataValue(old, value) {
+			changed[key] = value
+		}
+	}
+
+	// Rows hidden by nested keys, which only exist for metadata stored
+	// before it was stored losslessly, are not part of the document. They
+	// are deleted along with the keys they conflict with.
+	touched := map[string]bool{}
+	for key := range changed {
+		touched[key] = true
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			touched[key] = true
+		}
+	}
+	var removed []string
+	for _, row := range rows {
+		if _, ok := after[row.Key]; ok {
+			continue
+		}
+		if _, ok := before[row.Key]; ok || relatedMetadataKey(row.Key, touched) {
+			removed = append(removed, row.Key)
+		}
+	}
+	slices.Sort(removed)
+
+	err = deleteMetadata(tx, tenantID, processID, removed, source, now)
+	if err != nil {
+		return err
+	}
+	return writeMetadata(tx, tenantID, processID, changed, source, now)
+}
+
+// sameMetadataValue reports whether two metadata values are stored the same.
+func sameMetadataValue(a, b interface{}) bool {
+	aType, aValue := model.MarshalMetadataValue(a)
+	bType, bValue := model.MarshalMetadataValue(b)
+	return aType == bType && bytes.Equal(aValue, bValue)
+}
+
+// relatedMetadataKey reports whether key, one of its ancestors or one of its
+// descendants is in keys.
+func relatedMetadataKey(key string, keys map[string]bool) bool {
+	segments := model.SplitMetadataKey(key)
+	prefix := ""
+	for _, s := range segments {
+		prefix = model.JoinMetadataKey(prefix, s)
+		if keys[prefix] {
+			return true
+		}
+	}
+	for k := range keys {
+		if strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+)
+
+type getMetadataKeysResponse struct {
+	middleware.ResponseWrapper
+	Data []MetadataKeyStats `json:"data"`
+}
+
+func TestNumericStats(t *testing.T) {
+	s := numericStats([]weightedValue{{0, 1}, {2.5, 2}, {5, 1}, {10, 3}}, 4)
+	assert.Equal(t, &NumericStats{
+		Min: 0,
+		Max: 10,
+		Histogram: []HistogramBucket{
+			{Min: 0, Max: 2.5, Count: 1},
+			{Min: 2.5, Max: 5, Count: 2},
+			{Min: 5, Max: 7.5, Count: 1},
+			{Min: 7.5, Max: 10, Count: 3},
+		},
+	}, s)
+
+	// A single distinct value collapses into a single bucket.
+	s = numericStats([]weightedValue{{3, 2}}, 10)
+	assert.Equal(t, []HistogramBucket{{Min: 3, Max: 3, Count: 2}}, s.Histogram)
+}
+
+func TestAppReturnsMetadataKeys(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	registerProcessEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/process/new"
+	for _, body := range []string{
+		`{"project": "llm", "user_metadata": {"optimizer": "adam", "lr": 0.001, "model": {"layers": 12}}}`,
+		`{"project": "llm", "user_metadata": {"optimizer": "adam", "lr": 0.01, "model": {"layers": 24}}}`,
+		`{"project": "llm", "user_metadata": {"optimizer": "sgd", "lr": 0.01}}`,
+		`{"project": "vision", "user_metadata": {"optimizer": "sgd", "augment": true}}`,
+	} {
+		resp, err := httpC.Post(registerProcessEndpoint, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		read[createProcessResponse](t, resp)
+	}
+
+	keysEndpoint := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1/metadata/keys"
+	resp, err := httpC.Get(keysEndpoint + "?project=llm&buckets=2")
+	require.NoError(t, err)
+	gmr := read[getMetadataKeysResponse](t, resp)
+
+	require.Len(t, gmr.Data, 3)
+	lr, layers, optimizer := gmr.Data[0], gmr.Data[1], gmr.Data[2]
+
+	assert.Equal(t, "lr", lr.Key)
+	assert.Equal(t, 3, lr.ProcessCount)
+	assert.Equal(t, map[string]int{"float": 3}, lr.Types)
+	assert.Equal(t, []MetadataValueCount{{Value: 0.01, Type: "float", Count: 2}, {Value: 0.001, Type: "float", Count: 1}}, lr.TopValues)
+	require.NotNil(t, lr.Numeric)
+	assert.Equal(t, 0.001, lr.Numeric.Min)
+	assert.Equal(t, 0.01, lr.Numeric.Max)
+	assert.Len(t, lr.Numeric.Histogram, 2)
+
+	assert.Equal(t, "model.layers", layers.Key)
+	assert.Equal(t, 2, layers.ProcessCount)
+	assert.Equal(t, map[string]int{"int": 2}, layers.Types)
+	require.NotNil(t, layers.Numeric)
+	assert.Equal(t, 12.0, layers.Numeric.Min)
+	assert.Equal(t, 24.0, layers.Numeric.Max)
+
+	assert.Equal(t, "optimizer", optimizer.Key)
+	assert.Equal(t, 3, optimizer.ProcessCount)
+	assert.Equal(t, []MetadataValueCount{{Value: "adam", Type: "string", Count: 2}, {Value: "sgd", Type: "string", Count: 1}}, optimizer.TopValues)
+	assert.Nil(t, optimizer.Numeric)
+
+	// Without a project all keys of the tenant are returned, limited to the
+	// requested prefix.
+	resp, err = httpC.Get(keysEndpoint + "?prefix=aug&top=1")
+	require.NoError(t, err)
+	gmr = read[getMetadataKeysResponse](t, resp)
+	require.Len(t, gmr.Data, 1)
+	assert.Equal(t, "augment", gmr.Data[0].Key)
+	assert.Equal(t, []MetadataValueCount{{Value: true, Type: "bool", Count: 1}}, gmr.Data[0].TopValues)
+
+	resp, err = httpC.Get(keysEndpoint + "?top=-1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+type getMetadataHistoryResponse struct {
+	middleware.ResponseWrapper
+	Data []MetadataVersion `json:"data"`
+}
+
+func TestAppRecordsMetadataHistory(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json",
+		bytes.NewBufferString(`{"user_metadata": {"lr": 0.1, "optimizer": "adam"}}`))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+	processURL := baseURL + "/process/" + cpr.Data.ID.String()
+
+	beforeUpdate := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	resp, err = httpC.Post(processURL+"/update-metadata", "application/json",
+		bytes.NewBufferString(`{"source": "lr_scheduler", "user_metadata": {"lr": 0.01}}`))
+	require.NoError(t, err)
+	read[createProcessResponse](t, resp)
+
+	resp, err = httpC.Get(processURL + "/metadata/history?key=lr")
+	require.NoError(t, err)
+	ghr := read[getMetadataHistoryResponse](t, resp)
+	require.Len(t, ghr.Data, 2)
+	assert.Equal(t, 0.1, ghr.Data[0].Value)
+	assert.Equal(t, "register", ghr.Data[0].Source)
+	assert.Equal(t, 0.01, ghr.Data[1].Value)
+	assert.Equal(t, "lr_scheduler", ghr.Data[1].Source)
+	assert.Less(t, ghr.Data[0].Version, ghr.Data[1].Version)
+	assert.False(t, ghr.Data[1].Time.Before(ghr.Data[0].Time))
+
+	// Without a key, the writes of every key are returned.
+	resp, err = httpC.Get(processURL + "/metadata/history")
+	require.NoError(t, err)
+	ghr = read[getMetadataHistoryResponse](t, resp)
+	assert.Len(t, ghr.Data, 3)
+
+	// The current metadata has the new value, the metadata as of before the
+	// update still has the old one.
+	resp, err = httpC.Get(processURL)
+	require.NoError(t, err)
+	gpr := read[getProcessResponse](t, resp)
+	assert.Equal(t, map[string]interface{}{"lr": 0.01, "optimizer": "adam"}, gpr.Data.Metadata)
+
+	resp, err = httpC.Get(processURL + "?as_of=" + url.QueryEscape(beforeUpdate.Format(time.RFC3339Nano)))
+	require.NoError(t, err)
+	gpr = read[getProcessResponse](t, resp)
+	assert.Equal(t, map[string]interface{}{"lr": 0.1, "optimizer": "adam"}, gpr.Data.Metadata)
+
+	resp, err = httpC.Get(processURL + "?as_of=yesterday")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = httpC.Get(baseURL + "/process/" + uuid.NewString() + "/metadata/history")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestAppPatchesMetadata(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json",
+		bytes.NewBufferString(`{"user_metadata": {"optimizer": {"name": "adam", "betas": [0.9, 0.999]}, "lr": 0.1, "tags": ["a", "b"]}}`))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+	processURL := baseURL + "/process/" + cpr.Data.ID.String()
+
+	getMetadata := func() map[string]interface{} {
+		resp, err := httpC.Get(processURL)
+		require.NoError(t, err)
+		return read[getProcessResponse](t, resp).Data.Metadata
+	}
+	patch := func(contentType, body string) *http.Response {
+		resp, err := httpC.Post(processURL+"/update-metadata", contentType, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Null deletes keys along with everything nested under them, arrays are
+	// replaced as a whole.
+	read[createProcessResponse](t, patch("application/json",
+		`{"user_metadata": {"optimizer": {"betas": null}, "lr": null, "tags": ["c"], "epochs": 3}}`))
+	assert.Equal(t, map[string]interface{}{
+		"optimizer": map[string]interface{}{"name": "adam"},
+		"tags":      []interface{}{"c"},
+		"epochs":    3.0,
+	}, getMetadata())
+
+	resp, err = httpC.Get(processURL + "?metadata=flat")
+	require.NoError(t, err)
+	fgpr := read[getFlatProcessResponse](t, resp)
+	var keys []string
+	for _, kv := range fgpr.Data.Metadata {
+		keys = append(keys, kv.Key)
+	}
+	assert.ElementsMatch(t, []string{"optimizer.name", "tags", "tags.0", "epochs"}, keys)
+
+	resp, err = httpC.Get(processURL + "/metadata/history?key=lr")
+	require.NoError(t, err)
+	ghr := read[getMetadataHistoryResponse](t, resp)
+	require.Len(t, ghr.Data, 2)
+	assert.True(t, ghr.Data[1].Deleted)
+	assert.Nil(t, ghr.Data[1].Value)
+
+	// A bare merge patch.
+	read[createProcessResponse](t, patch("application/merge-patch+json", `{"optimizer": null}`))
+	assert.Equal(t, map[string]interface{}{"tags": []interface{}{"c"}, "epochs": 3.0}, getMetadata())
+
+	// A JSON patch.
+	read[createProcessResponse](t, patch("application/json-patch+json",
+		`[{"op": "test", "path": "/epochs", "value": 3}, {"op": "add", "path": "/tags/-", "value": "d"}, {"op": "move", "from": "/epochs", "path": "/max_epochs"}]`))
+	expected := map[string]interface{}{"tags": []interface{}{"c", "d"}, "max_epochs": 3.0}
+	assert.Equal(t, expected, getMetadata())
+
+	// Failing patches leave the metadata untouched, even if earlier
+	// operations succeeded.
+	resp = patch("application/json-patch+json",
+		`[{"op": "remove", "path": "/tags"}, {"op": "test", "path": "/max_epochs", "value": 4}]`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = patch("application/json-patch+json",
+		`[{"op": "remove", "path": "/tags"}, {"op": "remove", "path": "/missing"}]`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = patch("application/json", `{"user_metadata": ["not", "an", "object"]}`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	assert.Equal(t, expected, getMetadata())
+}
+
+func TestWriteMetadataSkipsUnchangedKeys(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.MetadataKV{}, &model.MetadataHistory{}))
+
+	processID := uuid.New()
+	now := time.Now()
+	require.NoError(t, writeMetadata(db, "0", processID, map[string]interface{}{"lr": 0.1, "optimizer": "adam"}, model.MetadataSourceRegister, now))
+	require.NoError(t, writeMetadata(db, "0", processID, map[string]interface{}{"lr": 0.01, "optimizer": "adam"}, "lr_scheduler", now.Add(time.Second)))
+
+	var history []model.MetadataHistory
+	require.NoError(t, db.Order("version").Find(&history).Error)
+	var keys []string
+	for _, h := range history {
+		keys = append(keys, h.Key)
+	}
+	assert.ElementsMatch(t, []string{"lr", "optimizer", "lr"}, keys)
+	assert.Equal(t, "lr_scheduler", history[2].Source)
+}
+
+func TestBackfillMetadataNumbers(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	// Metadata stored before the Number column existed.
+	require.NoError(t, db.AutoMigrate(&model.MetadataKV{}))
+	require.NoError(t, db.Migrator().DropColumn(&model.MetadataKV{}, "Number"))
+	processID := uuid.New()
+	require.NoError(t, db.Omit("Number").Create(&[]model.MetadataKV{
+		model.NewMetadataKV("0", processID, "layers", 12),
+		model.NewMetadataKV("0", processID, "lr", 0.5),
+		model.NewMetadataKV("0", processID, "huge", json.Number("1e400")),
+		model.NewMetadataKV("0", processID, "optimizer", "adam"),
+	}).Error)
+
+	backfilled, err := backfillMetadataNumbers(db)
+	require.NoError(t, err)
+	assert.Equal(t, 2, backfilled)
+
+	var rows []model.MetadataKV
+	require.NoError(t, db.Order("`key`").Find(&rows).Error)
+	numbers := map[string]*float64{}
+	for _, row := range rows {
+		numbers[row.Key] = row.Number
+	}
+	twelve, half := 12.0, 0.5
+	assert.Equal(t, map[string]*float64{"huge": nil, "layers": &twelve, "lr": &half, "optimizer": nil}, numbers)
+
+	// The column exists now, so values that aren't numbers are not looked at
+	// again.
+	backfilled, err = backfillMetadataNumbers(db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, backfilled)
+}
+
+func TestBackfillMetadataHistory(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Process{}, &model.MetadataKV{}, &model.MetadataHistory{}))
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	process := model.Process{ID: uuid.New(), TenantID: "0", StartTime: start}
+	require.NoError(t, db.Create(&process).Error)
+	require.NoError(t, db.Create(&[]model.MetadataKV{
+		model.NewMetadataKV("0", process.ID, "lr", 0.1),
+		// The process of this one was deleted.
+		model.NewMetadataKV("0", uuid.New(), "lr", 0.2),
+	}).Error)
+
+	backfilled, err := backfillMetadataHistory(db)
+	require.NoError(t, err)
+	assert.Equal(t, 1, backfilled)
+
+	var history []model.MetadataHistory
+	require.NoError(t, db.Find(&history).Error)
+	require.Len(t, history, 1)
+	assert.Equal(t, process.ID, history[0].ProcessID)
+	assert.True(t, start.Equal(history[0].Time))
+
+	// Backfilling again is a no-op.
+	backfilled, err = backfillMetadataHistory(db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, backfilled)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
+)
+
+// defaultSection is the section of metrics whose name has no section
+// prefix.
+const defaultSection = "default"
+
+// ModelMetricsQuery selects the metrics returned by /processes/model-metrics.
+// Every filter that is set must match; within a filter, any value may match.
+// A bare array of process IDs is also accepted as the body, selecting all
+// metrics of the processes.
+type ModelMetricsQuery struct {
+	ProcessIDs []uuid.UUID `json:"process_ids"`
+	// Metrics are metric names, where * matches any characters, including
+	// "/", and ? matches one, e.g. "train/*".
+	Metrics   []string `json:"metrics,omitempty"`
+	StepNames []string `json:"step_names,omitempty"`
+	// MinStep and MaxStep are the inclusive bounds of the steps.
+	MinStep *uint32 `json:"min_step,omitempty"`
+	MaxStep *uint32 `json:"max_step,omitempty"`
+	// Sections are the part of the metric names before the first "/", or
+	// "default" for names without one.
+	Sections []string `json:"sections,omitempty"`
+
+	// metricPatterns are the compiled Metrics.
+	metricPatterns []*regexp.Regexp
+}
+
+// parseModelMetricsQuery parses the body of a model metrics request.
+func parseModelMetricsQuery(r io.Reader) (ModelMetricsQuery, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return ModelMetricsQuery{}, middleware.ErrBadRequest(err)
+	}
+
+	var query ModelMetricsQuery
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &query.ProcessIDs)
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&query)
+	}
+	if err != nil {
+		return ModelMetricsQuery{}, middleware.ErrBadRequest(fmt.Errorf("invalid JSON: %v", err))
+	}
+
+	if query.MinStep != nil && query.MaxStep != nil && *query.MinStep > *query.MaxStep {
+		return ModelMetricsQuery{}, middleware.ErrBadRequest(fmt.Errorf("min_step must not be greater than max_step"))
+	}
+	for _, pattern := range query.Metrics {
+		if pattern == "" {
+			return ModelMetricsQuery{}, middleware.ErrBadRequest(fmt.Errorf("metric names must not be empty"))
+		}
+		query.metricPatterns = append(query.metricPatterns, globRegexp(pattern))
+	}
+	return query, nil
+}
+
+// where returns the SQL conditions of the filters, and their arguments, on
+// the model_metrics columns of the given table alias.
+func (q ModelMetricsQuery) where(alias string) (string, []interface{}) {
+	column := func(name string) string {
+		if alias == "" {
+			return name
+		}
+		return alias + "." + name
+	}
+
+	conditions := []string{column("process_id") + " IN ?"}
+	args := []interface{}{q.ProcessIDs}
+	if len(q.Metrics) > 0 {
+		var alternatives []string
+		for _, pattern := range q.Metrics {
+			alternatives = append(alternatives, column("metric_name")+" LIKE ? ESCAPE '!'")
+			args = append(args, globLike(pattern))
+		}
+		conditions = append(conditions, "("+strings.Join(alternatives, " OR ")+")")
+	}
+	if len(q.StepNames) > 0 {
+		conditions = append(conditions, column("step_name")+" IN ?")
+		args = append(args, q.StepNames)
+	}
+	if q.MinStep != nil {
+		conditions = append(conditions, column("step")+" >= ?")
+		args = append(args, *q.MinStep)
+	}
+	if q.MaxStep != nil {
+		conditions = append(conditions, column("step")+" <= ?")
+		args = append(args, *q.MaxStep)
+	}
+	if len(q.Sections) > 0 {
+		var alternatives []string
+		for _, section := range q.Sections {
+			if section == defaultSection {
+				alternatives = append(alternatives, column("metric_name")+" NOT LIKE '%/%'")
+			}
+			alternatives = append(alternatives, column("metric_name")+" LIKE ? ESCAPE '!'")
+			args = append(args, escapeLike(section)+"/%")
+		}
+		conditions = append(conditions, "("+strings.Join(alternatives, " OR ")+")")
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// matchesMetric reports whether a metric name matches the metric names and
+// sections of the query. LIKE is case-insensitive in SQLite and in MySQL's
+// default collations, so the results of the SQL filters are checked again.
+func (q ModelMetricsQuery) matchesMetric(name string) bool {
+	if len(q.Sections) > 0 {
+		section, _, found := strings.Cut(name, "/")
+		if !found {
+			section = defaultSection
+		}
+		if !slices.Contains(q.Sections, section) {
+			return false
+		}
+	}
+	if len(q.metricPatterns) == 0 {
+		return true
+	}
+	for _, pattern := range q.metricPatterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// globLike returns the LIKE pattern of a glob.
+func globLike(glob string) string {
+	return strings.NewReplacer("*", "%", "?", "_").Replace(escapeLike(glob))
+}
+
+// globRegexp returns the regular expression of a glob.
+func globRegexp(glob string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(glob)
+	quoted = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(quoted)
+	return regexp.MustCompile("^" + quoted + "$")
+}
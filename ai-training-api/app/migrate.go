@@ -0,0 +1,188 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+)
+
+// backfillMetadataNumbers adds the Number column to metadata stored before
+// it existed and fills it from the stored values. It only runs when the
+// column is missing, so values that aren't numbers a float64 can hold, such
+// as 1e400, are left NULL once rather than looked at on every start.
+func backfillMetadataNumbers(db *gorm.DB) (int, error) {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&model.MetadataKV{}) || migrator.HasColumn(&model.MetadataKV{}, "Number") {
+		return 0, nil
+	}
+
+	updated := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// SQLite adds the column as part of the transaction, so a failed
+		// backfill runs again on the next start.
+		if err := tx.Migrator().AddColumn(&model.MetadataKV{}, "Number"); err != nil {
+			return fmt.Errorf("error adding metadata number column: %w", err)
+		}
+
+		var rows []model.MetadataKV
+		err := tx.
+			Where("type IN ?", model.NumericMetadataTypes).
+			Find(&rows).Error
+		if err != nil {
+			return fmt.Errorf("error finding metadata to backfill: %w", err)
+		}
+		for _, row := range rows {
+			if !row.BackfillNumber() {
+				continue
+			}
+			err = tx.Model(&model.MetadataKV{}).
+				Where(&model.MetadataKV{
+					TenantID:  row.TenantID,
+					Key:       row.Key,
+					ProcessID: row.ProcessID,
+				}).
+				Update("number", row.Number).Error
+			if err != nil {
+				return fmt.Errorf("error backfilling metadata number: %w", err)
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// backfillMetadataHistory records the metadata written before its history
+// was kept, as if it had been written when its process started. Metadata
+// of processes that no longer exist is skipped, having no start time.
+func backfillMetadataHistory(db *gorm.DB) (int, error) {
+	var rows []struct {
+		model.MetadataKV
+		StartTime time.Time
+	}
+	err := db.
+		Table("metadata_kvs").
+		Select("metadata_kvs.*, processes.start_time AS start_time").
+		Joins("JOIN processes ON processes.id = metadata_kvs.process_id AND processes.tenant_id = metadata_kvs.tenant_id").
+		Where("NOT EXISTS (SELECT 1 FROM metadata_histories h WHERE h.tenant_id = metadata_kvs.tenant_id AND h.process_id = metadata_kvs.process_id AND h.`key` = metadata_kvs.`key`)").
+		Scan(&rows).Error
+	if err != nil {
+		return 0, fmt.Errorf("error finding metadata without history: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	history := make([]model.MetadataHistory, 0, len(rows))
+	for _, row := range rows {
+		history = append(history, model.NewMetadataHistory(row.MetadataKV, model.MetadataSourceBackfill, row.StartTime))
+	}
+	err = db.CreateInBatches(history, 100).Error
+	if err != nil {
+		return 0, fmt.Errorf("error backfilling metadata history: %w", err)
+	}
+	return len(history), nil
+}
+
+// legacyMetricValueColumn holds metric values stored as text while they are
+// converted to numbers.
+const legacyMetricValueColumn = "metric_value_text"
+
+// migrateMetricValues converts metric values stored as text, before they
+// were stored as numbers, into the double metric_value column. Text that
+// does not parse as a number is stored as NaN.
+func migrateMetricValues(db *gorm.DB) (int, error) {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&model.ModelMetrics{}) {
+		return 0, nil
+	}
+	columns, err := migrator.ColumnTypes(&model.ModelMetrics{})
+	if err != nil {
+		return 0, fmt.Errorf("error reading ModelMetrics columns: %w", err)
+	}
+	legacy := migrator.HasColumn(&model.ModelMetrics{}, legacyMetricValueColumn)
+	for _, c := range columns {
+		typeName := strings.ToLower(c.DatabaseTypeName())
+		if c.Name() == "metric_value" && (strings.Contains(typeName, "char") || strings.Contains(typeName, "text")) {
+			err = migrator.RenameColumn(&model.ModelMetrics{}, "metric_value", legacyMetricValueColumn)
+			if err != nil {
+				return 0, fmt.Errorf("error renaming metric_value: %w", err)
+			}
+			legacy = true
+		}
+	}
+	if !legacy {
+		return 0, nil
+	}
+
+	err = db.AutoMigrate(&model.ModelMetrics{})
+	if err != nil {
+		return 0, fmt.Errorf("error migrating ModelMetrics table: %w", err)
+	}
+
+	// Values are converted by a few statements over the whole table rather
+	// than row by row. Text values were written from JSON numbers, so they
+	// are numbers if they are valid JSON numbers, which SQLite and MySQL can
+	// both tell.
+	jsonType := "CASE WHEN JSON_VALID(" + legacyMetricValueColumn + ") THEN UPPER(JSON_TYPE(" + legacyMetricValueColumn + ")) ELSE '' END"
+	numberTypes := []string{"INTEGER", "UNSIGNED INTEGER", "REAL", "DOUBLE", "DECIMAL"}
+	converted := 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.ModelMetrics{}).
+			Where(jsonType+" IN ?", numberTypes).
+			Updates(map[string]interface{}{
+				"metric_value": gorm.Expr("CAST(" + legacyMetricValueColumn + " AS DOUBLE)"),
+				"non_finite":   "",
+			})
+		if result.Error != nil {
+			return fmt.Errorf("error converting metric values: %w", result.Error)
+		}
+		converted += int(result.RowsAffected)
+
+		// Numbers out of the range of doubles are cast to infinities by
+		// SQLite.
+		for nonFinite, where := range map[string]string{
+			model.MetricValuePosInf: "metric_value > ?",
+			model.MetricValueNegInf: "metric_value < -?",
+		} {
+			err := tx.Model(&model.ModelMetrics{}).
+				Where(where, math.MaxFloat64).
+				Updates(map[string]interface{}{
+					"metric_value": nil,
+					"non_finite":   nonFinite,
+				}).Error
+			if err != nil {
+				return fmt.Errorf("error converting infinite metric values: %w", err)
+			}
+		}
+
+		result = tx.Model(&model.ModelMetrics{}).
+			Where(jsonType+" NOT IN ?", numberTypes).
+			Updates(map[string]interface{}{
+				"metric_value": nil,
+				"non_finite":   model.MetricValueNaN,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("error converting metric values that are not numbers: %w", result.Error)
+		}
+		converted += int(result.RowsAffected)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = migrator.DropColumn(&model.ModelMetrics{}, legacyMetricValueColumn)
+	if err != nil {
+		return 0, fmt.Errorf("error dropping %s: %w", legacyMetricValueColumn, err)
+	}
+	return converted, nil
+}
@@ -1,27 +1,174 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"mime"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
 	"github.com/grafana/ai-training-o11y/ai-training-api/model"
 )
 
+// X-axes metrics can be plotted against.
+const (
+	xAxisStep = "step"
+	// The wall-clock time of the points.
+	xAxisTime = "time"
+	// The time since the process started, in seconds.
+	xAxisRelativeTime = "relative_time"
+)
+
 // Incoming format is an array of these
 type AddModelMetricsPayload struct {
 	StepName  string                 `json:"step_name"`
 	StepValue uint32                 `json:"step_value"`
-	Metrics   map[string]json.Number `json:"metrics"`
+	Metrics   map[string]MetricValue `json:"metrics"`
+	// Timestamp is when the point was logged. It defaults to when the server
+	// received it.
+	Timestamp *MetricTimestamp `json:"timestamp,omitempty"`
+	// Axes are the positions of the point on other axes, e.g. the epoch and
+	// the tokens seen at this step. Metrics can be plotted against any of
+	// them without sending the values again.
+	Axes map[string]float64 `json:"axes,omitempty"`
+}
+
+// MetricTimestamp is the wall-clock time of a metric point, sent either as an
+// RFC 3339 string or as seconds since the Unix epoch, like Python's
+// time.time().
+type MetricTimestamp time.Time
+
+func (t *MetricTimestamp) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp: %w", err)
+		}
+		*t = MetricTimestamp(parsed)
+		return nil
+	}
+
+	seconds, err := strconv.ParseFloat(string(b), 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return fmt.Errorf("invalid timestamp %s", b)
+	}
+	sec, frac := math.Modf(seconds)
+	*t = MetricTimestamp(time.Unix(int64(sec), int64(frac*1e9)))
+	return nil
+}
+
+func (t MetricTimestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).Format(time.RFC3339Nano))
+}
+
+// MetricValue is a metric value sent by a client. Besides JSON numbers it
+// accepts NaN and infinities as strings, e.g. "NaN", "Infinity" or "-inf",
+// since diverging losses are exactly what needs to be seen.
+type MetricValue float64
+
+func (v *MetricValue) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || !(math.IsNaN(f) || math.IsInf(f, 0)) {
+			return fmt.Errorf("metric value %q is neither a number, NaN nor an infinity", s)
+		}
+		*v = MetricValue(f)
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(string(b), 64)
+	// Numbers too large for a float64 are infinities.
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("metric value %s is not a number", b)
+	}
+	*v = MetricValue(f)
+	return nil
+}
+
+func (v MetricValue) MarshalJSON() ([]byte, error) {
+	f := float64(v)
+	switch {
+	case math.IsNaN(f):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(f, 1):
+		return []byte(`"Infinity"`), nil
+	case math.IsInf(f, -1):
+		return []byte(`"-Infinity"`), nil
+	}
+	return json.Marshal(f)
+}
+
+// quoteNonFiniteNumbers quotes the bare NaN, Infinity and -Infinity tokens
+// that Python's json module writes by default, which are not valid JSON, so
+// that they decode as MetricValue strings.
+func quoteNonFiniteNumbers(b []byte) []byte {
+	if !bytes.Contains(b, []byte("NaN")) && !bytes.Contains(b, []byte("Infinity")) {
+		return b
+	}
+
+	out := make([]byte, 0, len(b)+16)
+	inString := false
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if inString {
+			out = append(out, c)
+			switch c {
+			case '\\':
+				if i+1 < len(b) {
+					i++
+					out = append(out, b[i])
+				}
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+			out = append(out, c)
+			continue
+		}
+		quoted := false
+		for _, token := range []string{"NaN", "Infinity", "-Infinity"} {
+			if bytes.HasPrefix(b[i:], []byte(token)) {
+				out = append(out, '"')
+				out = append(out, token...)
+				out = append(out, '"')
+				i += len(token) - 1
+				quoted = true
+				break
+			}
+		}
+		if !quoted {
+			out = append(out, c)
+		}
+	}
+	return out
 }
 
 type AddModelMetricsResponse struct {
@@ -36,7 +183,24 @@ type Result struct {
 	MetricName  string
 	StepName    string
 	Step        uint32
-	MetricValue *string // Pointer to allow for NULL values
+	MetricValue *float64 // Pointer to allow for NULL values
+	// Set for NaN and infinities, see model.ModelMetrics.
+	NonFinite *string
+	// Only set when plotting against time.
+	Timestamp        *time.Time
+	ProcessStartTime *time.Time
+	// Only set when plotting against a named axis.
+	X *float64
+}
+
+// Value returns the metric value, or nil if the process has no value at
+// this step.
+func (r Result) Value() *float64 {
+	if r.NonFinite != nil && *r.NonFinite != "" {
+		f := model.JoinMetricValue(sql.NullFloat64{}, *r.NonFinite)
+		return &f
+	}
+	return r.MetricValue
 }
 
 // This is for return
@@ -46,6 +210,47 @@ type Field struct {
 	Name   string        `json:"name"`
 	Type   string        `json:"type"`
 	Values []interface{} `json:"values"`
+	// Labels tell apart the fields of a frame derived from the same
+	// series, like Grafana's.
+	Labels map[string]string `json:"labels,omitempty"`
+	// JSON cannot represent NaN and infinities, they are sent as null and
+	// their indices listed here, like Grafana does for its data frames.
+	Entities *FieldEntities `json:"entities,omitempty"`
+}
+
+// FieldEntities lists the indices of the values of a field that are NaN or
+// infinite.
+type FieldEntities struct {
+	NaN    []int `json:"NaN,omitempty"`
+	Inf    []int `json:"Inf,omitempty"`
+	NegInf []int `json:"NegInf,omitempty"`
+}
+
+// appendNumber appends a number to the field, recording NaN and infinities
+// in its entities. A nil value is appended as null.
+func (f *Field) appendNumber(v *float64) {
+	if v == nil {
+		f.Values = append(f.Values, nil)
+		return
+	}
+	if !math.IsNaN(*v) && !math.IsInf(*v, 0) {
+		f.Values = append(f.Values, *v)
+		return
+	}
+
+	if f.Entities == nil {
+		f.Entities = &FieldEntities{}
+	}
+	i := len(f.Values)
+	switch {
+	case math.IsNaN(*v):
+		f.Entities.NaN = append(f.Entities.NaN, i)
+	case *v > 0:
+		f.Entities.Inf = append(f.Entities.Inf, i)
+	default:
+		f.Entities.NegInf = append(f.Entities.NegInf, i)
+	}
+	f.Values = append(f.Values, nil)
 }
 
 type DataFrame []Field
@@ -66,27 +271,61 @@ func (a *App) addModelMetrics(tenantID string, req *http.Request) (interface{},
 		return nil, err
 	}
 
-	// Validate ProcessID exists
-	if err := a.validateProcessExists(req.Context(), processID); err != nil {
+	// Validate ProcessID exists for the tenant
+	if _, err := a.findProcess(req.Context(), tenantID, processID); err != nil {
 		return nil, err
 	}
 
+	policy := req.URL.Query().Get("on_conflict")
+	switch policy {
+	case "":
+		policy = conflictError
+	case conflictError, conflictIgnore, conflictOverwrite:
+	default:
+		return nil, middleware.ErrBadRequest(fmt.Errorf("on_conflict must be %q, %q or %q", conflictError, conflictIgnore, conflictOverwrite))
+	}
+
+	start := time.Now()
+	defer func() {
+		a.ingestDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	body, err := metricsRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	// NDJSON bodies are streamed, the size limit applies to each line.
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == contentTypeNDJSON {
+		return a.streamModelMetrics(req.Context(), tenantID, processID, body, policy)
+	}
+
 	// Parse and validate the request body
+	req.Body = http.MaxBytesReader(nil, io.NopCloser(body), a.maxMetricsPayloadBytes)
 	metricsData, err := parseAndValidateModelMetricsRequest(req)
 	if err != nil {
 		return nil, err
 	}
+	a.ingestRows.Observe(float64(len(metricsData)))
 
-	// Save the metrics and get the count of created metrics
-	createdCount, err := a.saveModelMetrics(req.Context(), tenantID, processID, metricsData)
+	// Save the metrics and get the counts of what happened to them
+	result, err := a.saveModelMetrics(req.Context(), tenantID, processID, metricsData, policy)
 	if err != nil {
 		return nil, err
 	}
 
-	// Return a JSON response with success message and count of metrics inserted
+	// Logging metrics is a sign of life, so it counts as a heartbeat.
+	if err := a.touchProcess(req.Context(), tenantID, processID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Return a JSON response with success message and counts of metrics
 	response := map[string]interface{}{
-		"message":        "Metrics successfully added",
-		"metricsCreated": createdCount,
+		"message":            "Metrics successfully added",
+		"metricsCreated":     result.Created,
+		"metricsSkipped":     result.Skipped,
+		"metricsOverwritten": result.Overwritten,
 	}
 
 	return response, nil
@@ -116,46 +355,290 @@ func extractAndValidateProcessID(req *http.Request) (uuid.UUID, error) {
 	return processID, nil
 }
 
-func (a *App) validateProcessExists(ctx context.Context, processID uuid.UUID) error {
-	var process model.Process
-	if err := a.db(ctx).First(&process, "id = ?", processID).Error; err != nil {
+// findProcess returns a process of the tenant.
+func (a *App) findProcess(ctx context.Context, tenantID string, processID uuid.UUID) (model.Process, error) {
+	process := model.Process{}
+	err := a.db(ctx).
+		Where(&model.Process{
+			TenantID: tenantID,
+			ID:       processID,
+		}).First(&process).Error
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return middleware.ErrNotFound(fmt.Errorf("process not found"))
+			return model.Process{}, middleware.ErrNotFound(fmt.Errorf("process not found"))
 		}
-		return fmt.Errorf("error checking process: %w", err)
+		return model.Process{}, fmt.Errorf("error finding process: %w", err)
 	}
-	return nil
+	return process, nil
 }
 
 func parseAndValidateModelMetricsRequest(req *http.Request) ([]model.ModelMetrics, error) {
 	var metricsData []AddModelMetricsPayload
 
-	decoder := json.NewDecoder(req.Body)
-
-	if err := decoder.Decode(&metricsData); err != nil {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, middleware.ErrPayloadTooLarge(fmt.Errorf("request body is larger than %d bytes", tooLarge.Limit))
+		}
+		return nil, middleware.ErrBadRequest(err)
+	}
+	if err := json.Unmarshal(quoteNonFiniteNumbers(body), &metricsData); err != nil {
 		return nil, middleware.ErrBadRequest(fmt.Errorf("invalid JSON: %v", err))
 	}
 
 	var metrics []model.ModelMetrics
 
+	receivedAt := time.Now()
 	for _, item := range metricsData {
-		for metricName, metricValue := range item.Metrics {
-			metric := model.ModelMetrics{
-				MetricName:  metricName,
-				StepName:    item.StepName,
-				Step:        item.StepValue,
-				MetricValue: metricValue.String(),
-			}
+		itemMetrics, err := modelMetricsFromPayload(item, receivedAt)
+		if err != nil {
+			return nil, middleware.ErrBadRequest(err)
+		}
+		metrics = append(metrics, itemMetrics...)
+	}
+
+	return metrics, nil
+}
+
+// modelMetricsFromPayload returns the points of a payload. Points without a
+// timestamp are stamped with receivedAt.
+func modelMetricsFromPayload(item AddModelMetricsPayload, receivedAt time.Time) ([]model.ModelMetrics, error) {
+	timestamp := receivedAt
+	if item.Timestamp != nil {
+		timestamp = time.Time(*item.Timestamp).Local()
+	}
+
+	metrics := make([]model.ModelMetrics, 0, len(item.Metrics))
+	for metricName, metricValue := range item.Metrics {
+		metric := model.ModelMetrics{
+			MetricName: metricName,
+			StepName:   item.StepName,
+			Step:       item.StepValue,
+			Timestamp:  sql.NullTime{Time: timestamp, Valid: true},
+			Axes:       item.Axes,
+		}
+		metric.SetValue(float64(metricValue))
+
+		if err := validateModelMetric(&metric); err != nil {
+			return nil, fmt.Errorf("invalid metric: %v", err)
+		}
+
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+// metricsRequestBody returns the body of a model metrics request,
+// decompressed if it is gzipped. The caller must close it.
+func metricsRequestBody(req *http.Request) (io.ReadCloser, error) {
+	switch encoding := req.Header.Get("Content-Encoding"); encoding {
+	case "", "identity":
+		return req.Body, nil
+	case "gzip":
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return nil, middleware.ErrBadRequest(fmt.Errorf("invalid gzip body: %w", err))
+		}
+		return gz, nil
+	default:
+		return nil, middleware.ErrBadRequest(fmt.Errorf("unsupported Content-Encoding %q", encoding))
+	}
+}
+
+// LineError is a line of an NDJSON request that was rejected.
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// chunkedIngest saves the points of a streamed request in chunks as they
+// are added, so memory use does not grow with the size of the request. Each
+// chunk is saved in its own transaction, so when saving one fails the
+// previous chunks are kept and total counts them. With the ignore or
+// overwrite conflict policies the request can be retried as is, with the
+// error policy only the points after the saved chunks can be.
+type chunkedIngest struct {
+	app       *App
+	ctx       context.Context
+	tenantID  string
+	processID uuid.UUID
+	policy    string
+
+	chunk  []model.ModelMetrics
+	points int
+	total  ingestResult
+}
+
+func (c *chunkedIngest) add(metrics []model.ModelMetrics) error {
+	c.points += len(metrics)
+	c.chunk = append(c.chunk, metrics...)
+	if len(c.chunk) < streamChunkPoints {
+		return nil
+	}
+	return c.flush()
+}
+
+func (c *chunkedIngest) flush() error {
+	if len(c.chunk) == 0 {
+		return nil
+	}
+	result, err := c.app.saveModelMetrics(c.ctx, c.tenantID, c.processID, c.chunk, c.policy)
+	if err != nil {
+		return err
+	}
+	c.total.Created += result.Created
+	c.total.Skipped += result.Skipped
+	c.total.Overwritten += result.Overwritten
+	c.chunk = c.chunk[:0]
+	return nil
+}
+
+// finish saves the remaining points and records the request.
+func (c *chunkedIngest) finish() error {
+	if err := c.flush(); err != nil {
+		return err
+	}
+	c.app.ingestRows.Observe(float64(c.points))
+
+	// Logging metrics is a sign of life, so it counts as a heartbeat.
+	return c.app.touchProcess(c.ctx, c.tenantID, c.processID, time.Now())
+}
+
+// saveProcessesMetrics saves points of several processes of the tenant, for
+// receivers whose requests aren't about a single process. Points of processes
+// that don't exist are skipped, and their number returned.
+func (a *App) saveProcessesMetrics(ctx context.Context, tenantID string, metricsByProcess map[uuid.UUID][]model.ModelMetrics, policy string) (ingestResult, int, error) {
+	var total ingestResult
+	if len(metricsByProcess) == 0 {
+		a.ingestRows.Observe(0)
+		return total, 0, nil
+	}
+
+	processIDs := make([]uuid.UUID, 0, len(metricsByProcess))
+	for processID := range metricsByProcess {
+		processIDs = append(processIDs, processID)
+	}
+	slices.SortFunc(processIDs, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	processes, err := findTenantProcesses(a.db(ctx), tenantID, processIDs)
+	if err != nil {
+		return ingestResult{}, 0, err
+	}
 
-			if err := validateModelMetric(&metric); err != nil {
-				return nil, middleware.ErrBadRequest(fmt.Errorf("invalid metric: %v", err))
+	points, unknown := 0, 0
+	now := time.Now()
+	for _, processID := range processIDs {
+		metrics := metricsByProcess[processID]
+		points += len(metrics)
+		if _, ok := processes[processID]; !ok {
+			unknown += len(metrics)
+			continue
+		}
+
+		result, err := a.saveModelMetrics(ctx, tenantID, processID, metrics, policy)
+		if err != nil {
+			return ingestResult{}, 0, err
+		}
+		total.Created += result.Created
+		total.Skipped += result.Skipped
+		total.Overwritten += result.Overwritten
+
+		if err := a.touchProcess(ctx, tenantID, processID, now); err != nil {
+			return ingestResult{}, 0, err
+		}
+	}
+	a.ingestRows.Observe(float64(points))
+	return total, unknown, nil
+}
+
+// streamModelMetrics saves the points of an NDJSON body, with one
+// AddModelMetricsPayload per line, as it is read. Memory use is bounded by
+// the chunk and line sizes rather than the size of the body. Invalid lines
+// are skipped and reported by line number.
+//
+// Points are saved in chunks, see chunkedIngest. If the request fails after
+// some were saved, the error response still has the counts of the saved
+// points and the number of lines they came from, so that a client using the
+// error policy can resend only the following lines.
+func (a *App) streamModelMetrics(ctx context.Context, tenantID string, processID uuid.UUID, body io.Reader, policy string) (interface{}, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), int(a.maxMetricsPayloadBytes))
+
+	ingest := &chunkedIngest{app: a, ctx: ctx, tenantID: tenantID, processID: processID, policy: policy}
+	var (
+		line       int
+		linesSaved int
+		rejected   int
+		lineErrors []LineError
+	)
+	response := func(message string) map[string]interface{} {
+		return map[string]interface{}{
+			"message":            message,
+			"metricsCreated":     ingest.total.Created,
+			"metricsSkipped":     ingest.total.Skipped,
+			"metricsOverwritten": ingest.total.Overwritten,
+			"linesSaved":         linesSaved,
+			"linesRejected":      rejected,
+			"lineErrors":         lineErrors,
+		}
+	}
+	// failed returns err, with the counts of the points that were saved
+	// before it if there are any.
+	failed := func(err error) (interface{}, error) {
+		if linesSaved == 0 {
+			return nil, err
+		}
+		return response("Metrics partially added"), err
+	}
+
+	receivedAt := time.Now()
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var item AddModelMetricsPayload
+		err := json.Unmarshal(quoteNonFiniteNumbers(text), &item)
+		if err != nil {
+			err = fmt.Errorf("invalid JSON: %v", err)
+		}
+		var metrics []model.ModelMetrics
+		if err == nil {
+			metrics, err = modelMetricsFromPayload(item, receivedAt)
+		}
+		if err != nil {
+			rejected++
+			if len(lineErrors) < maxReportedLineErrors {
+				lineErrors = append(lineErrors, LineError{Line: line, Error: err.Error()})
 			}
+			continue
+		}
 
-			metrics = append(metrics, metric)
+		if err := ingest.add(metrics); err != nil {
+			return failed(err)
+		}
+		// The chunk is empty once it was saved.
+		if len(ingest.chunk) == 0 {
+			linesSaved = line
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return failed(middleware.ErrPayloadTooLarge(fmt.Errorf("line %d is longer than %d bytes", line+1, a.maxMetricsPayloadBytes)))
+		}
+		return failed(middleware.ErrBadRequest(fmt.Errorf("error reading line %d: %w", line+1, err)))
+	}
+	if err := ingest.finish(); err != nil {
+		if len(ingest.chunk) == 0 {
+			linesSaved = line
+		}
+		return failed(err)
+	}
+	linesSaved = line
 
-	return metrics, nil
+	return response("Metrics successfully added"), nil
 }
 
 func validateModelMetric(m *model.ModelMetrics) error {
@@ -169,114 +652,539 @@ func validateModelMetric(m *model.ModelMetrics) error {
 	if m.Step == 0 {
 		return fmt.Errorf("step must be a positive number")
 	}
-	if m.MetricValue == "" {
-		return fmt.Errorf("metric value cannot be empty")
+	for name, value := range m.Axes {
+		if len(name) == 0 || len(name) > 32 {
+			return fmt.Errorf("axis name must be between 1 and 32 characters")
+		}
+		if name == m.StepName {
+			return fmt.Errorf("axis %q is the step name", name)
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("axis %q must be a finite number", name)
+		}
 	}
 	return nil
 }
 
-func (a *App) saveModelMetrics(ctx context.Context, tenantID string, processID uuid.UUID, metricsData []model.ModelMetrics) (int, error) {
-	var createdCount int
+// Policies for points of a request that were already logged, selected with
+// the on_conflict query parameter.
+const (
+	conflictError     = "error"
+	conflictIgnore    = "ignore"
+	conflictOverwrite = "overwrite"
+)
+
+// existingKeysBatchSize is how many points are looked up per query. A
+// lookup has three variables per point, well below the limits of the bundled
+// SQLite (32766) and MySQL (65535).
+const existingKeysBatchSize = 300
 
-	// Start a transaction
-	tx := a.db(ctx).Begin()
-	if tx.Error != nil {
-		return 0, fmt.Errorf("error starting transaction: %w", tx.Error)
+// insertBatchSize is how many rows are written per INSERT statement. A
+// statement has one variable per column per row, which must stay below the
+// limits of the bundled SQLite (32766) and MySQL (65535).
+const insertBatchSize = 500
+
+const (
+	contentTypeNDJSON = "application/x-ndjson"
+	// streamChunkPoints is how many points of a streamed request are
+	// decoded before they are saved.
+	streamChunkPoints = 10 * insertBatchSize
+	// maxReportedLineErrors caps the rejected lines listed in a response.
+	maxReportedLineErrors = 100
+)
+
+// ingestResult counts what happened to the points of a request. They add up
+// to the number of points sent.
+type ingestResult struct {
+	Created     int
+	Skipped     int
+	Overwritten int
+}
+
+// metricKey is the primary key of a point within a process.
+type metricKey struct {
+	metricName string
+	stepName   string
+	step       uint32
+}
+
+func keyOf(m model.ModelMetrics) metricKey {
+	return metricKey{metricName: m.MetricName, stepName: m.StepName, step: m.Step}
+}
+
+// saveModelMetrics saves the points of a request in one transaction. Points
+// that were already logged, either before or earlier in the same request, are
+// handled according to the policy: with conflictError nothing is saved and a
+// conflict is returned, with conflictIgnore the first value is kept and with
+// conflictOverwrite the last one. This makes retrying a request safe.
+func (a *App) saveModelMetrics(ctx context.Context, tenantID string, processID uuid.UUID, metricsData []model.ModelMetrics, policy string) (ingestResult, error) {
+	var result ingestResult
+	err := a.db(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := existingMetricKeys(tx, tenantID, processID, metricsData)
+		if err != nil {
+			return err
+		}
+
+		var rows []model.ModelMetrics
+		index := map[metricKey]int{}
+		conflicts := 0
+		for _, metric := range metricsData {
+			metric.TenantID = tenantID
+			metric.ProcessID = processID
+			key := keyOf(metric)
+
+			if i, ok := index[key]; ok {
+				switch policy {
+				case conflictError:
+					return middleware.ErrConflict(fmt.Errorf("metric %q is logged twice at %s %d", key.metricName, key.stepName, key.step))
+				case conflictIgnore:
+					result.Skipped++
+				case conflictOverwrite:
+					rows[i] = metric
+					result.Overwritten++
+				}
+				continue
+			}
+
+			switch {
+			case !existing[key]:
+				result.Created++
+			case policy == conflictError:
+				conflicts++
+				continue
+			case policy == conflictIgnore:
+				result.Skipped++
+				continue
+			case policy == conflictOverwrite:
+				result.Overwritten++
+			}
+			index[key] = len(rows)
+			rows = append(rows, metric)
+		}
+		if conflicts > 0 {
+			return middleware.ErrConflict(fmt.Errorf("%d metric points were already logged", conflicts))
+		}
+
+		// Points logged concurrently by another request are handled by the
+		// database, according to the same policy.
+		onConflict := clause.OnConflict{DoNothing: true}
+		if policy == conflictOverwrite {
+			onConflict = clause.OnConflict{
+				Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "process_id"}, {Name: "metric_name"}, {Name: "step_name"}, {Name: "step"}},
+				DoUpdates: clause.AssignmentColumns([]string{"metric_value", "non_finite", "timestamp"}),
+			}
+		}
+		var conflictClause clause.Interface
+		if policy != conflictError {
+			conflictClause = onConflict
+		}
+		if err := insertModelMetrics(tx, rows, conflictClause); err != nil {
+			if policy == conflictError && isDuplicateKey(tx, err) {
+				return middleware.ErrConflict(errors.New("metric points were logged concurrently by another request"))
+			}
+			return fmt.Errorf("error creating model metrics: %w", err)
+		}
+
+		return saveMetricAxes(tx, tenantID, processID, metricsData, policy == conflictOverwrite)
+	})
+	if err != nil {
+		return ingestResult{}, err
 	}
+	return result, nil
+}
 
+// isDuplicateKey reports whether err, as returned by the database, is a
+// violation of a primary key or unique index.
+func isDuplicateKey(tx *gorm.DB, err error) bool {
+	translator, ok := tx.Dialector.(gorm.ErrorTranslator)
+	return ok && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey)
+}
+
+// existingMetricKeys returns which of the points were already logged.
+func existingMetricKeys(tx *gorm.DB, tenantID string, processID uuid.UUID, metricsData []model.ModelMetrics) (map[metricKey]bool, error) {
+	existing := map[metricKey]bool{}
+	for start := 0; start < len(metricsData); start += existingKeysBatchSize {
+		batch := metricsData[start:min(start+existingKeysBatchSize, len(metricsData))]
+		keys := make([][]interface{}, 0, len(batch))
+		for _, m := range batch {
+			keys = append(keys, []interface{}{m.MetricName, m.StepName, m.Step})
+		}
+
+		var found []model.ModelMetrics
+		err := tx.Model(&model.ModelMetrics{}).
+			Select("metric_name", "step_name", "step").
+			Where("tenant_id = ? AND process_id = ? AND (metric_name, step_name, step) IN ?", tenantID, processID, keys).
+			Find(&found).Error
+		if err != nil {
+			return nil, fmt.Errorf("error finding existing model metrics: %w", err)
+		}
+		for _, m := range found {
+			existing[keyOf(m)] = true
+		}
+	}
+	return existing, nil
+}
+
+// saveMetricAxes saves the axes of the points. They are stored once per
+// point, no matter how many metrics were logged at it, so axes that were
+// already saved, e.g. with other metrics of the same step, are kept unless
+// overwrite is set.
+func saveMetricAxes(tx *gorm.DB, tenantID string, processID uuid.UUID, metricsData []model.ModelMetrics, overwrite bool) error {
+	onConflict := clause.OnConflict{DoNothing: true}
+	if overwrite {
+		onConflict = clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "process_id"}, {Name: "step_name"}, {Name: "step"}, {Name: "axis_name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value"}),
+		}
+	}
+
+	type axisKey struct {
+		stepName string
+		step     uint32
+		axisName string
+	}
+	axes := map[axisKey]int{}
+	var rows []model.MetricAxis
 	for _, metric := range metricsData {
-		metric.TenantID = tenantID
-		metric.ProcessID = processID
+		for name, value := range metric.Axes {
+			axis := model.MetricAxis{
+				TenantID:  tenantID,
+				ProcessID: processID,
+				StepName:  metric.StepName,
+				Step:      metric.Step,
+				AxisName:  name,
+				Value:     value,
+			}
+			key := axisKey{stepName: metric.StepName, step: metric.Step, axisName: name}
+			if i, ok := axes[key]; ok {
+				if overwrite {
+					rows[i] = axis
+				}
+				continue
+			}
+			axes[key] = len(rows)
+			rows = append(rows, axis)
+		}
+	}
 
-		// Save to database
-		if err := tx.Create(&metric).Error; err != nil {
-			tx.Rollback()
-			return 0, fmt.Errorf("error creating model metric: %w", err)
+	if err := insertInBatches(tx.Clauses(onConflict), rows, insertBatchSize); err != nil {
+		return fmt.Errorf("error creating metric axes: %w", err)
+	}
+	return nil
+}
+
+// insertInBatches inserts the rows with one multi-row INSERT per batch.
+func insertInBatches[T any](tx *gorm.DB, rows []T, batchSize int) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(rows, batchSize).Error
+}
+
+// modelMetricsColumns are the columns insertModelMetrics writes, in the
+// order of the values of each row.
+var modelMetricsColumns = []string{"tenant_id", "process_id", "metric_name", "step_name", "step", "metric_value", "non_finite", "timestamp"}
+
+// insertModelMetrics inserts the points with one multi-row INSERT per batch of
+// insertBatchSize rows, followed by onConflict if it isn't nil. For large
+// requests gorm's reflection over every row costs more than the inserts
+// themselves, so the statement is built once, prepared once and executed with
+// values read straight from the points. It still runs through tx, so the
+// inserts are logged and traced like any other statement.
+func insertModelMetrics(tx *gorm.DB, rows []model.ModelMetrics, onConflict clause.Interface) error {
+	var (
+		fullBatch *preparedConn
+		prepared  *gorm.DB
+	)
+	if len(rows) >= insertBatchSize {
+		query, err := insertStatement(tx, insertBatchSize, onConflict)
+		if err != nil {
+			return err
 		}
-		createdCount++
+		fullBatch = &preparedConn{ConnPool: tx.Statement.ConnPool, query: query}
+		defer fullBatch.Close()
+		// A new context gives the session its own statement, so that tx keeps
+		// its connection.
+		prepared = tx.WithContext(tx.Statement.Context)
+		prepared.Statement.ConnPool = fullBatch
 	}
 
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		return 0, fmt.Errorf("error committing transaction: %w", err)
+	args := make([]interface{}, 0, len(modelMetricsColumns)*min(len(rows), insertBatchSize))
+	var (
+		processID  uuid.UUID
+		processStr string
+	)
+	for start := 0; start < len(rows); start += insertBatchSize {
+		batch := rows[start:min(start+insertBatchSize, len(rows))]
+		args = args[:0]
+		for _, m := range batch {
+			if m.ProcessID != processID || processStr == "" {
+				processID, processStr = m.ProcessID, m.ProcessID.String()
+			}
+			var value, timestamp interface{}
+			if m.MetricValue.Valid {
+				value = m.MetricValue.Float64
+			}
+			if m.Timestamp.Valid {
+				timestamp = m.Timestamp.Time
+			}
+			args = append(args, m.TenantID, processStr, m.MetricName, m.StepName, int64(m.Step), value, m.NonFinite, timestamp)
+		}
+
+		if len(batch) == insertBatchSize {
+			if err := prepared.Exec(fullBatch.query, args...).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		query, err := insertStatement(tx, len(batch), onConflict)
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec(query, args...).Error; err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	return createdCount, nil
+// preparedConn runs query as a statement it prepares on first use, and any
+// other statement on the connection it wraps.
+type preparedConn struct {
+	gorm.ConnPool
+	query string
+	stmt  *sql.Stmt
 }
 
-func getCompleteMetrics(ctx context.Context, db *gorm.DB, tenantID string, processes []string) ([]Result, error) {
-	// Convert []string to []uuid.UUID
-	uuidProcesses := make([]uuid.UUID, 0, len(processes))
-	for _, p := range processes {
-		uid, err := uuid.Parse(p)
+func (c *preparedConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if query != c.query {
+		return c.ConnPool.ExecContext(ctx, query, args...)
+	}
+	if c.stmt == nil {
+		stmt, err := c.ConnPool.PrepareContext(ctx, query)
 		if err != nil {
-			return nil, fmt.Errorf("invalid UUID string: %s", p)
+			return nil, err
 		}
-		uuidProcesses = append(uuidProcesses, uid)
+		c.stmt = stmt
+	}
+	return c.stmt.ExecContext(ctx, args...)
+}
+
+// Close closes the prepared statement, if there is one.
+func (c *preparedConn) Close() error {
+	if c.stmt == nil {
+		return nil
+	}
+	return c.stmt.Close()
+}
+
+// insertStatement returns an INSERT of rows points into model_metrics, in the
+// dialect of the database.
+func insertStatement(tx *gorm.DB, rows int, onConflict clause.Interface) (string, error) {
+	stmt := &gorm.Statement{DB: tx, Clauses: map[string]clause.Clause{}}
+	// The schema is needed by the MySQL dialect to ignore conflicts.
+	if err := stmt.Parse(&model.ModelMetrics{}); err != nil {
+		return "", fmt.Errorf("error parsing model metrics schema: %w", err)
+	}
+
+	values := clause.Values{
+		Columns: make([]clause.Column, len(modelMetricsColumns)),
+		Values:  make([][]interface{}, rows),
+	}
+	for i, name := range modelMetricsColumns {
+		values.Columns[i] = clause.Column{Name: name}
+	}
+	// Each value is written as a placeholder.
+	placeholders := make([]interface{}, len(modelMetricsColumns))
+	for i := range values.Values {
+		values.Values[i] = placeholders
 	}
 
+	stmt.AddClause(clause.Insert{})
+	stmt.AddClause(values)
+	if onConflict != nil {
+		stmt.AddClause(onConflict)
+	}
+	stmt.Build("INSERT", "VALUES", "ON CONFLICT")
+	return stmt.SQL.String(), nil
+}
+
+// getStepSeries returns the series of the metrics selected by the query,
+// positioned at their steps. Each point is read once, in the order of the
+// primary key, so the points of a series come out together and sorted by
+// step; aligning the series of a panel is left to alignSeries rather than
+// joined in the query.
+func getStepSeries(ctx context.Context, db *gorm.DB, tenantID string, query ModelMetricsQuery) ([]panelSeries, error) {
+	where, args := query.where("")
+
+	rows, err := db.WithContext(ctx).
+		Model(&model.ModelMetrics{}).
+		Select("process_id, metric_name, step_name, step, metric_value, non_finite").
+		Where("tenant_id = ?", tenantID).
+		Where(where, args...).
+		Order("process_id, metric_name, step_name, step").
+		Rows()
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	var (
+		series []panelSeries
+		// Scanned into for every row, so they are allocated once.
+		processID            uuid.UUID
+		metricName, stepName sql.RawBytes
+		step                 int64
+		value                sql.NullFloat64
+		nonFinite            sql.RawBytes
+	)
+	for rows.Next() {
+		if err := rows.Scan(&processID, &metricName, &stepName, &step, &value, &nonFinite); err != nil {
+			return nil, fmt.Errorf("error reading query results: %v", err)
+		}
+
+		// Names are only copied when a new series starts.
+		n := len(series)
+		if n == 0 || series[n-1].processID != processID ||
+			series[n-1].metricName != string(metricName) || series[n-1].stepName != string(stepName) {
+			series = append(series, panelSeries{
+				metricName:   string(metricName),
+				stepName:     string(stepName),
+				metricSeries: metricSeries{processID: processID},
+			})
+			n++
+		}
+		v := model.JoinMetricValue(value, string(nonFinite))
+		series[n-1].xs = append(series[n-1].xs, float64(step))
+		series[n-1].values = append(series[n-1].values, &v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading query results: %v", err)
+	}
+
+	return slices.DeleteFunc(series, func(s panelSeries) bool {
+		return !query.matchesMetric(s.metricName)
+	}), nil
+}
+
+// getTimedMetrics returns the metric points of the processes that have a
+// timestamp, along with the start time of their process.
+func getTimedMetrics(ctx context.Context, db *gorm.DB, tenantID string, query ModelMetricsQuery) ([]Result, error) {
+	where, args := query.where("m")
+
 	var results []Result
+	err := db.WithContext(ctx).
+		Table("model_metrics AS m").
+		Select("m.process_id, m.metric_name, m.step_name, m.step, m.metric_value, m.non_finite, m.timestamp, p.start_time AS process_start_time").
+		Joins("JOIN processes p ON p.id = m.process_id AND p.tenant_id = m.tenant_id").
+		Where("m.tenant_id = ? AND m.timestamp IS NOT NULL", tenantID).
+		Where(where, args...).
+		Order("m.timestamp").
+		Scan(&results).Error
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+	return filterResults(results, query), nil
+}
+
+// getAxisMetrics returns the metric points of the processes along with their
+// position on the named axis. Points logged with the axis as their step name
+// are positioned at their step, others at the value of their additional
+// axis, if they have one.
+func getAxisMetrics(ctx context.Context, db *gorm.DB, tenantID string, query ModelMetricsQuery, axis string) ([]Result, error) {
+	where, args := query.where("m")
 
-	query := `
-		WITH process_metrics AS (
-			SELECT DISTINCT process_id, metric_name, step_name
-			FROM model_metrics
-			WHERE tenant_id = ? AND process_id IN ?
-		),
-		metric_steps AS (
-			SELECT metric_name, step_name, step
-			FROM model_metrics
-			WHERE tenant_id = ? AND process_id IN ?
-		),
-		all_combinations AS (
-			SELECT DISTINCT
-				pm.process_id,
-				pm.metric_name,
-				pm.step_name,
-				ms.step
-			FROM 
-				process_metrics pm
-			JOIN
-				metric_steps ms ON pm.metric_name = ms.metric_name AND pm.step_name = ms.step_name
-		)
-		SELECT DISTINCT
-			ac.process_id,
-			ac.metric_name,
-			ac.step_name,
-			ac.step,
-			d.metric_value
-		FROM 
-			all_combinations ac
-		LEFT JOIN
-			model_metrics d ON d.tenant_id = ? 
-							AND d.process_id = ac.process_id 
-							AND d.metric_name = ac.metric_name 
-							AND d.step_name = ac.step_name
-							AND d.step = ac.step
-		ORDER BY ac.step ASC
-    `
-
-	err := db.WithContext(ctx).Raw(query, tenantID, uuidProcesses, tenantID, uuidProcesses, tenantID).Scan(&results).Error
+	var results []Result
+	err := db.WithContext(ctx).
+		Table("model_metrics AS m").
+		Select("m.process_id, m.metric_name, m.step_name, m.step, m.metric_value, m.non_finite, "+
+			"CASE WHEN m.step_name = ? THEN m.step ELSE a.value END AS x", axis).
+		Joins("LEFT JOIN metric_axes a ON a.tenant_id = m.tenant_id AND a.process_id = m.process_id "+
+			"AND a.step_name = m.step_name AND a.step = m.step AND a.axis_name = ?", axis).
+		Where("m.tenant_id = ? AND (m.step_name = ? OR a.value IS NOT NULL)", tenantID, axis).
+		Where(where, args...).
+		Scan(&results).Error
 	if err != nil {
 		return nil, fmt.Errorf("error executing query: %v", err)
 	}
-	return results, nil
+	return filterResults(results, query), nil
 }
 
-func transformMetricsData(results []Result) GetModelMetricsResponse {
-	// Group results by metric_name and step_name
-	// This makes it easy to separate panels: each []Result is a panel
-	// This "only" leaves turning it into a DataFrame to send to the frontend
-	groupedData := make(map[string]map[string][]Result)
-	for _, r := range results {
-		if _, ok := groupedData[r.MetricName]; !ok {
-			groupedData[r.MetricName] = make(map[string][]Result)
+// filterResults drops the results whose metric doesn't match the query,
+// which the case-insensitive SQL filters may have kept.
+func filterResults(results []Result, query ModelMetricsQuery) []Result {
+	return slices.DeleteFunc(results, func(r Result) bool {
+		return !query.matchesMetric(r.MetricName)
+	})
+}
+
+// transformMetricsDataByAxis turns results into one panel per metric,
+// plotted against the named axis.
+func transformMetricsDataByAxis(results []Result, axis string, mode string) GetModelMetricsResponse {
+	// All points share the axis, whatever step name they were logged with.
+	for i := range results {
+		results[i].StepName = axis
+	}
+	series := seriesOf(results, func(r Result) (float64, bool) {
+		if r.X == nil {
+			return 0, false
+		}
+		return *r.X, true
+	})
+	return buildMetricsResponse(series, func(_ string, series []metricSeries) DataFrame {
+		return alignSeries(Field{Name: axis, Type: "number"}, series, mode, func(x float64) interface{} { return x })
+	})
+}
+
+// transformStepSeries turns series positioned at their steps into one panel
+// per metric and step name.
+func transformStepSeries(series []panelSeries, mode string) GetModelMetricsResponse {
+	return buildMetricsResponse(series, func(stepName string, series []metricSeries) DataFrame {
+		return alignSeries(Field{Name: stepName, Type: "number"}, series, mode, func(x float64) interface{} { return uint32(x) })
+	})
+}
+
+// transformMetricsDataByTime turns results into one panel per metric and step
+// name, plotted against the time of the points, as milliseconds since the
+// epoch like Grafana's time fields, or the seconds since their process
+// started.
+func transformMetricsDataByTime(results []Result, xAxis string, mode string) GetModelMetricsResponse {
+	xField := Field{Name: xAxis, Type: "time"}
+	xValue := func(x float64) interface{} { return int64(x) }
+	if xAxis == xAxisRelativeTime {
+		xField.Type = "number"
+		xValue = func(x float64) interface{} { return x }
+	}
+
+	series := seriesOf(results, func(r Result) (float64, bool) {
+		if r.Timestamp == nil {
+			return 0, false
+		}
+		if xAxis != xAxisRelativeTime {
+			return float64(r.Timestamp.UnixMilli()), true
 		}
+		if r.ProcessStartTime == nil {
+			return 0, false
+		}
+		return r.Timestamp.Sub(*r.ProcessStartTime).Seconds(), true
+	})
+	return buildMetricsResponse(series, func(_ string, series []metricSeries) DataFrame {
+		return alignSeries(xField, series, mode, xValue)
+	})
+}
 
-		if _, ok := groupedData[r.MetricName][r.StepName]; !ok {
-			groupedData[r.MetricName][r.StepName] = make([]Result, 0)
+// buildMetricsResponse groups series into sections and panels, using frame
+// to build the data frame of each panel from its series.
+func buildMetricsResponse(series []panelSeries, frame func(stepName string, series []metricSeries) DataFrame) GetModelMetricsResponse {
+	// Group series by metric_name and step_name
+	// This makes it easy to separate panels: each []metricSeries is a panel
+	// This "only" leaves turning it into a DataFrame to send to the frontend
+	groupedData := make(map[string]map[string][]metricSeries)
+	for _, s := range series {
+		if _, ok := groupedData[s.metricName]; !ok {
+			groupedData[s.metricName] = make(map[string][]metricSeries)
 		}
-		groupedData[r.MetricName][r.StepName] = append(groupedData[r.MetricName][r.StepName], r)
+		groupedData[s.metricName][s.stepName] = append(groupedData[s.metricName][s.stepName], s.metricSeries)
 	}
 
 	response := GetModelMetricsResponse{
@@ -284,7 +1192,7 @@ func transformMetricsData(results []Result) GetModelMetricsResponse {
 	}
 
 	for metricName, stepData := range groupedData {
-		sectionName := "default"
+		sectionName := defaultSection
 		displayName := metricName
 		first, second, hasSectionName := strings.Cut(metricName, "/")
 		if hasSectionName {
@@ -294,59 +1202,11 @@ func transformMetricsData(results []Result) GetModelMetricsResponse {
 
 		panels := []Panel{}
 		// Construct an individual panel
-		for stepName, metricRows := range stepData {
-			// Create empty panel
-
-			newPanel := Panel{
+		for stepName, panelSeries := range stepData {
+			panels = append(panels, Panel{
 				Title:  displayName,
-				Series: make([]Field, 0),
-			}
-
-			// Create step field
-			stepField := Field{
-				Name:   stepName,
-				Type:   "number",
-				Values: make([]interface{}, 0),
-			}
-
-			steps := make([]interface{}, 0)
-			lastStep := uint32(0)
-			processFields := make(map[string]*Field)
-			processOrder := make([]string, 0)
-			for _, row := range metricRows {
-				// Add new steps
-				if row.Step != lastStep {
-					steps = append(steps, row.Step)
-					lastStep = row.Step
-				}
-
-				// Check if the field already exists
-				if _, ok := processFields[row.ProcessID.String()]; !ok {
-					processFields[row.ProcessID.String()] = &Field{
-						Name:   row.ProcessID.String(),
-						Type:   "number",
-						Values: make([]interface{}, 0),
-					}
-
-					processOrder = append(processOrder, row.ProcessID.String())
-				}
-				// Append the value to the field
-				processFields[row.ProcessID.String()].Values = append(processFields[row.ProcessID.String()].Values, row.MetricValue)
-			}
-
-			stepField.Values = steps
-			// turn processFields into a slice
-			processFieldsSlice := make([]Field, 0)
-			for _, proc_id := range processOrder {
-				processFieldsSlice = append(processFieldsSlice, *processFields[proc_id])
-			}
-
-			newPanel.Series = append(newPanel.Series, stepField)
-			// unpack the processFieldsSlice and append it into stepField.values too
-
-			// This is broken and I am going to ask claude how to fix it
-			newPanel.Series = append(newPanel.Series, processFieldsSlice...)
-			panels = append(panels, newPanel)
+				Series: frame(stepName, panelSeries),
+			})
 		}
 
 		response.Sections[sectionName] = append(response.Sections[sectionName], panels...)
@@ -362,21 +1222,86 @@ func transformMetricsData(results []Result) GetModelMetricsResponse {
 	return response
 }
 
+// getModelMetrics returns the metrics selected by the ModelMetricsQuery in
+// the body as panels. The x_axis query parameter selects what they are
+// plotted against: the step (default), the wall-clock time or the time since
+// the process started. Alternatively the axis query parameter plots them against a named
+// axis, either a step name or an additional axis sent with the points. The
+// max_data_points query parameter downsamples the panels, with the method
+// selected by the downsample query parameter. The align query parameter
+// selects how the series of a panel are aligned on its x-axis, and the
+// smoothing query parameter adds smoothed series next to the raw ones.
 func (a *App) getModelMetrics(tenantID string, req *http.Request) (interface{}, error) {
-	// parse request body into an array
-	var processes []string
-
-	decoder := json.NewDecoder(req.Body)
-	if err := decoder.Decode(&processes); err != nil {
-		return nil, middleware.ErrBadRequest(fmt.Errorf("invalid JSON: %v", err))
+	query := req.URL.Query()
+	xAxis := query.Get("x_axis")
+	switch xAxis {
+	case "":
+		xAxis = xAxisStep
+	case xAxisStep, xAxisTime, xAxisRelativeTime:
+	default:
+		return nil, middleware.ErrBadRequest(fmt.Errorf("x_axis must be %q, %q or %q", xAxisStep, xAxisTime, xAxisRelativeTime))
+	}
+	axis := query.Get("axis")
+	if axis != "" && query.Get("x_axis") != "" {
+		return nil, middleware.ErrBadRequest(fmt.Errorf("only one of x_axis and axis can be set"))
+	}
+	maxDataPoints := 0
+	if v := query.Get("max_data_points"); v != "" {
+		var err error
+		maxDataPoints, err = strconv.Atoi(v)
+		if err != nil || maxDataPoints < 2 {
+			return nil, middleware.ErrBadRequest(fmt.Errorf("max_data_points must be an integer of at least 2"))
+		}
+	}
+	downsample, err := parseDownsampleMethod(query.Get("downsample"))
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+	if query.Get("downsample") != "" && maxDataPoints == 0 {
+		return nil, middleware.ErrBadRequest(fmt.Errorf("downsample requires max_data_points"))
+	}
+	align, err := parseAlignMode(query.Get("align"))
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
+	}
+	smoothing, err := parseSmoothingSpec(query)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(err)
 	}
 
-	results, err := getCompleteMetrics(req.Context(), a.db(req.Context()), tenantID, processes)
+	metricsQuery, err := parseModelMetricsQuery(req.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error getting complete metrics: %w", err)
+		return nil, err
 	}
 
-	transformedMetricsData := transformMetricsData(results)
+	var transformedMetricsData GetModelMetricsResponse
+	switch {
+	case axis != "":
+		results, err := getAxisMetrics(req.Context(), a.db(req.Context()), tenantID, metricsQuery, axis)
+		if err != nil {
+			return nil, fmt.Errorf("error getting axis metrics: %w", err)
+		}
+		transformedMetricsData = transformMetricsDataByAxis(results, axis, align)
+	case xAxis == xAxisStep:
+		series, err := getStepSeries(req.Context(), a.db(req.Context()), tenantID, metricsQuery)
+		if err != nil {
+			return nil, fmt.Errorf("error getting step metrics: %w", err)
+		}
+		transformedMetricsData = transformStepSeries(series, align)
+	default:
+		results, err := getTimedMetrics(req.Context(), a.db(req.Context()), tenantID, metricsQuery)
+		if err != nil {
+			return nil, fmt.Errorf("error getting timed metrics: %w", err)
+		}
+		transformedMetricsData = transformMetricsDataByTime(results, xAxis, align)
+	}
+	// Series are smoothed at full resolution.
+	if smoothing != nil {
+		smoothResponse(transformedMetricsData, *smoothing)
+	}
+	if maxDataPoints > 0 {
+		downsampleResponse(transformedMetricsData, maxDataPoints, downsample)
+	}
 
 	return transformedMetricsData, nil // Return results instead of nil
 }
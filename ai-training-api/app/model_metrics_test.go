@@ -3,13 +3,17 @@ package api
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/google/go-cmp/cmp"
@@ -20,6 +24,8 @@ import (
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
+	db "github.com/grafana/ai-training-o11y/ai-training-api/internal"
+	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
 	"github.com/grafana/ai-training-o11y/ai-training-api/model"
 )
 
@@ -100,7 +106,7 @@ func TestExtractAndValidateProcessID(t *testing.T) {
 	}
 }
 
-func TestValidateProcessExists(t *testing.T) {
+func TestFindProcess(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
@@ -119,6 +125,7 @@ func TestValidateProcessExists(t *testing.T) {
 	tests := []struct {
 		name           string
 		setupDB        func(*gorm.DB)
+		tenantID       string
 		expectedErrMsg string
 	}{
 		{
@@ -130,6 +137,13 @@ func TestValidateProcessExists(t *testing.T) {
 					t.Fatalf("Failed to create process: %v", result.Error)
 				}
 			},
+			tenantID: "0",
+		},
+		{
+			name:           "Process of another tenant",
+			setupDB:        func(db *gorm.DB) {},
+			tenantID:       "other",
+			expectedErrMsg: "process not found",
 		},
 		{
 			name:           "Process does not exist",
@@ -144,15 +158,15 @@ func TestValidateProcessExists(t *testing.T) {
 
 			// Use a fixed UUID for testing to ensure we're looking for the correct process
 			testUUID := uuid.New()
-			if tt.name == "Process exists" {
-				process := model.Process{ID: testUUID}
+			if tt.tenantID != "" {
+				process := model.Process{ID: testUUID, TenantID: tt.tenantID}
 				result := db.Create(&process)
 				if result.Error != nil {
 					t.Fatalf("Failed to create process: %v", result.Error)
 				}
 			}
 
-			err := app.validateProcessExists(context.Background(), testUUID)
+			_, err := app.findProcess(context.Background(), "0", testUUID)
 
 			if tt.expectedErrMsg != "" {
 				assert.Error(t, err)
@@ -178,8 +192,8 @@ func TestParseAndValidateModelMetricsRequest(t *testing.T) {
 				{
 					StepName:  "train",
 					StepValue: 1,
-					Metrics: map[string]json.Number{
-						"accuracy": "0.95",
+					Metrics: map[string]MetricValue{
+						"accuracy": 0.95,
 					},
 				},
 			},
@@ -192,9 +206,9 @@ func TestParseAndValidateModelMetricsRequest(t *testing.T) {
 				{
 					StepName:  "train",
 					StepValue: 1,
-					Metrics: map[string]json.Number{
-						"accuracy": "0.95",
-						"loss":     "0.05",
+					Metrics: map[string]MetricValue{
+						"accuracy": 0.95,
+						"loss":     0.05,
 					},
 				},
 			},
@@ -207,15 +221,15 @@ func TestParseAndValidateModelMetricsRequest(t *testing.T) {
 				{
 					StepName:  "train",
 					StepValue: 1,
-					Metrics: map[string]json.Number{
-						"accuracy": "0.95",
+					Metrics: map[string]MetricValue{
+						"accuracy": 0.95,
 					},
 				},
 				{
 					StepName:  "validate",
 					StepValue: 1,
-					Metrics: map[string]json.Number{
-						"accuracy": "0.93",
+					Metrics: map[string]MetricValue{
+						"accuracy": 0.93,
 					},
 				},
 			},
@@ -280,19 +294,20 @@ func TestParseAndValidateModelMetricsRequest(t *testing.T) {
 }
 
 // Helper function to create test metric data
-func createTestMetric(t *testing.T, stepName string, stepValue uint32, metricName string, metricValue string) model.ModelMetrics {
-	return model.ModelMetrics{
-		StepName:    stepName,
-		Step:        stepValue,
-		MetricName:  metricName,
-		MetricValue: metricValue,
+func createTestMetric(t *testing.T, stepName string, stepValue uint32, metricName string, metricValue float64) model.ModelMetrics {
+	m := model.ModelMetrics{
+		StepName:   stepName,
+		Step:       stepValue,
+		MetricName: metricName,
 	}
+	m.SetValue(metricValue)
+	return m
 }
 
 func TestTransformMetricsData(t *testing.T) {
-	// Helper function to create a string pointer
-	strPtr := func(s string) *string {
-		return &s
+	// Helper function to create a float pointer
+	floatPtr := func(f float64) *float64 {
+		return &f
 	}
 
 	// Test case
@@ -303,17 +318,17 @@ func TestTransformMetricsData(t *testing.T) {
 	}{
 		name: "Mixed metrics with different sections and step names",
 		input: []Result{
-			{TenantID: "1", ProcessID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), MetricName: "training/accuracy", StepName: "Epoch", Step: 1, MetricValue: strPtr("0.75")},
-			{TenantID: "1", ProcessID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), MetricName: "training/secondary_measure", StepName: "Epoch", Step: 1, MetricValue: strPtr("1.0")},
-			{TenantID: "1", ProcessID: uuid.MustParse("22222222-2222-2222-2222-222222222222"), MetricName: "training/accuracy", StepName: "Epoch", Step: 1, MetricValue: strPtr("0.70")},
-			{TenantID: "1", ProcessID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), MetricName: "training/accuracy", StepName: "Epoch", Step: 2, MetricValue: strPtr("0.80")},
-			{TenantID: "1", ProcessID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), MetricName: "training/secondary_measure", StepName: "Epoch", Step: 2, MetricValue: strPtr("2.0")},
-			{TenantID: "1", ProcessID: uuid.MustParse("22222222-2222-2222-2222-222222222222"), MetricName: "training/accuracy", StepName: "Epoch", Step: 2, MetricValue: strPtr("0.78")},
-			{TenantID: "1", ProcessID: uuid.MustParse("33333333-3333-3333-3333-333333333333"), MetricName: "evaluation/f1_score", StepName: "Step", Step: 1, MetricValue: strPtr("0.65")},
-			{TenantID: "1", ProcessID: uuid.MustParse("33333333-3333-3333-3333-333333333333"), MetricName: "evaluation/f1_score", StepName: "Step", Step: 2, MetricValue: strPtr("0.70")},
-			{TenantID: "1", ProcessID: uuid.MustParse("44444444-4444-4444-4444-444444444444"), MetricName: "custom_metric", StepName: "Iteration", Step: 1, MetricValue: strPtr("10")},
-			{TenantID: "1", ProcessID: uuid.MustParse("44444444-4444-4444-4444-444444444444"), MetricName: "custom_metric", StepName: "Iteration", Step: 2, MetricValue: strPtr("15")},
-			{TenantID: "1", ProcessID: uuid.MustParse("55555555-5555-5555-5555-555555555555"), MetricName: "test/accuracy", StepName: "Step", Step: 1, MetricValue: strPtr("0.9")},
+			{TenantID: "1", ProcessID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), MetricName: "training/accuracy", StepName: "Epoch", Step: 1, MetricValue: floatPtr(0.75)},
+			{TenantID: "1", ProcessID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), MetricName: "training/secondary_measure", StepName: "Epoch", Step: 1, MetricValue: floatPtr(1.0)},
+			{TenantID: "1", ProcessID: uuid.MustParse("22222222-2222-2222-2222-222222222222"), MetricName: "training/accuracy", StepName: "Epoch", Step: 1, MetricValue: floatPtr(0.70)},
+			{TenantID: "1", ProcessID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), MetricName: "training/accuracy", StepName: "Epoch", Step: 2, MetricValue: floatPtr(0.80)},
+			{TenantID: "1", ProcessID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), MetricName: "training/secondary_measure", StepName: "Epoch", Step: 2, MetricValue: floatPtr(2.0)},
+			{TenantID: "1", ProcessID: uuid.MustParse("22222222-2222-2222-2222-222222222222"), MetricName: "training/accuracy", StepName: "Epoch", Step: 2, MetricValue: floatPtr(0.78)},
+			{TenantID: "1", ProcessID: uuid.MustParse("33333333-3333-3333-3333-333333333333"), MetricName: "evaluation/f1_score", StepName: "Step", Step: 1, MetricValue: floatPtr(0.65)},
+			{TenantID: "1", ProcessID: uuid.MustParse("33333333-3333-3333-3333-333333333333"), MetricName: "evaluation/f1_score", StepName: "Step", Step: 2, MetricValue: floatPtr(0.70)},
+			{TenantID: "1", ProcessID: uuid.MustParse("44444444-4444-4444-4444-444444444444"), MetricName: "custom_metric", StepName: "Iteration", Step: 1, MetricValue: floatPtr(10)},
+			{TenantID: "1", ProcessID: uuid.MustParse("44444444-4444-4444-4444-444444444444"), MetricName: "custom_metric", StepName: "Iteration", Step: 2, MetricValue: floatPtr(15)},
+			{TenantID: "1", ProcessID: uuid.MustParse("55555555-5555-5555-5555-555555555555"), MetricName: "test/accuracy", StepName: "Step", Step: 1, MetricValue: floatPtr(0.9)},
 		},
 		expected: GetModelMetricsResponse{
 			Sections: map[string][]Panel{
@@ -322,15 +337,15 @@ func TestTransformMetricsData(t *testing.T) {
 						Title: "accuracy",
 						Series: DataFrame{
 							{Name: "Epoch", Type: "number", Values: []interface{}{uint32(1), uint32(2)}},
-							{Name: "11111111-1111-1111-1111-111111111111", Type: "number", Values: []interface{}{strPtr("0.75"), strPtr("0.80")}},
-							{Name: "22222222-2222-2222-2222-222222222222", Type: "number", Values: []interface{}{strPtr("0.70"), strPtr("0.78")}},
+							{Name: "11111111-1111-1111-1111-111111111111", Type: "number", Values: []interface{}{0.75, 0.8}},
+							{Name: "22222222-2222-2222-2222-222222222222", Type: "number", Values: []interface{}{0.7, 0.78}},
 						},
 					},
 					{
 						Title: "secondary_measure",
 						Series: DataFrame{
 							{Name: "Epoch", Type: "number", Values: []interface{}{uint32(1), uint32(2)}},
-							{Name: "11111111-1111-1111-1111-111111111111", Type: "number", Values: []interface{}{strPtr("1.0"), strPtr("2.0")}},
+							{Name: "11111111-1111-1111-1111-111111111111", Type: "number", Values: []interface{}{1.0, 2.0}},
 						},
 					},
 				},
@@ -339,7 +354,7 @@ func TestTransformMetricsData(t *testing.T) {
 						Title: "f1_score",
 						Series: DataFrame{
 							{Name: "Step", Type: "number", Values: []interface{}{uint32(1), uint32(2)}},
-							{Name: "33333333-3333-3333-3333-333333333333", Type: "number", Values: []interface{}{strPtr("0.65"), strPtr("0.70")}},
+							{Name: "33333333-3333-3333-3333-333333333333", Type: "number", Values: []interface{}{0.65, 0.7}},
 						},
 					},
 				},
@@ -355,7 +370,7 @@ func TestTransformMetricsData(t *testing.T) {
 							{
 								Name:   "55555555-5555-5555-5555-555555555555",
 								Type:   "number",
-								Values: []interface{}{strPtr("0.9")},
+								Values: []interface{}{0.9},
 							},
 						},
 					},
@@ -365,7 +380,7 @@ func TestTransformMetricsData(t *testing.T) {
 						Title: "custom_metric",
 						Series: DataFrame{
 							{Name: "Iteration", Type: "number", Values: []interface{}{uint32(1), uint32(2)}},
-							{Name: "44444444-4444-4444-4444-444444444444", Type: "number", Values: []interface{}{strPtr("10"), strPtr("15")}},
+							{Name: "44444444-4444-4444-4444-444444444444", Type: "number", Values: []interface{}{10.0, 15.0}},
 						},
 					},
 				},
@@ -374,9 +389,410 @@ func TestTransformMetricsData(t *testing.T) {
 	}
 
 	t.Run(testCase.name, func(t *testing.T) {
-		result := transformMetricsData(testCase.input)
+		result := transformMetricsData(testCase.input, alignUnion)
 		if diff := cmp.Diff(testCase.expected, result); diff != "" {
 			t.Errorf("transformMetricsData() mismatch (-want +got):\n%s", diff)
 		}
 	})
 }
+
+func TestParseNonFiniteMetricValues(t *testing.T) {
+	// Python's json module writes NaN and infinities as bare tokens.
+	body := `[{"step_name": "step", "step_value": 1, "metrics": {"loss": NaN, "grad_norm": Infinity, "min_grad": -Infinity, "lr": "-inf", "NaN_count": 2, "huge": 1e400}}]`
+	req, err := http.NewRequest("POST", "/metrics", strings.NewReader(body))
+	require.NoError(t, err)
+
+	metrics, err := parseAndValidateModelMetricsRequest(req)
+	require.NoError(t, err)
+
+	values := map[string]float64{}
+	for _, m := range metrics {
+		values[m.MetricName] = m.Float64()
+	}
+	assert.True(t, math.IsNaN(values["loss"]))
+	assert.True(t, math.IsInf(values["grad_norm"], 1))
+	assert.True(t, math.IsInf(values["min_grad"], -1))
+	assert.True(t, math.IsInf(values["lr"], -1))
+	assert.Equal(t, 2.0, values["NaN_count"])
+	assert.True(t, math.IsInf(values["huge"], 1))
+
+	req, err = http.NewRequest("POST", "/metrics", strings.NewReader(`[{"step_name": "step", "step_value": 1, "metrics": {"loss": "high"}}]`))
+	require.NoError(t, err)
+	_, err = parseAndValidateModelMetricsRequest(req)
+	assert.ErrorContains(t, err, `metric value "high" is neither a number, NaN nor an infinity`)
+}
+
+func TestTransformMetricsDataWithNonFiniteValues(t *testing.T) {
+	processID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	nonFinite := func(s string) *string { return &s }
+	value := func(f float64) *float64 { return &f }
+	results := []Result{
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 1, MetricValue: value(2.5), NonFinite: nonFinite("")},
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 2, NonFinite: nonFinite(model.MetricValueNaN)},
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 3, NonFinite: nonFinite(model.MetricValuePosInf)},
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 4},
+		{ProcessID: processID, MetricName: "loss", StepName: "step", Step: 5, NonFinite: nonFinite(model.MetricValueNegInf)},
+	}
+
+	response := transformMetricsData(results, alignUnion)
+	require.Len(t, response.Sections["default"], 1)
+	field := response.Sections["default"][0].Series[1]
+	assert.Equal(t, []interface{}{2.5, nil, nil, nil, nil}, field.Values)
+	assert.Equal(t, &FieldEntities{NaN: []int{1}, Inf: []int{2}, NegInf: []int{4}}, field.Entities)
+
+	// The response must be valid JSON.
+	_, err := json.Marshal(response)
+	require.NoError(t, err)
+}
+
+// legacyModelMetrics is ModelMetrics as it was stored before metric values
+// were stored as numbers.
+type legacyModelMetrics struct {
+	TenantID    string    `gorm:"not null;primaryKey"`
+	ProcessID   uuid.UUID `gorm:"type:char(36);not null;primaryKey"`
+	MetricName  string    `gorm:"size:32;not null;primaryKey"`
+	StepName    string    `gorm:"size:32;not null;primaryKey"`
+	Step        uint32    `gorm:"not null;primaryKey"`
+	MetricValue string    `gorm:"size:64;not null"`
+}
+
+func (legacyModelMetrics) TableName() string { return "model_metrics" }
+
+func TestMigrateMetricValues(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&legacyModelMetrics{}))
+
+	processID := uuid.New()
+	for i, v := range []string{"0.5", "0.5", "12", "1e400", "garbage", "-1e400", "1.2.3"} {
+		require.NoError(t, db.Create(&legacyModelMetrics{
+			TenantID: "0", ProcessID: processID, MetricName: "loss", StepName: "step", Step: uint32(i + 1), MetricValue: v,
+		}).Error)
+	}
+
+	converted, err := migrateMetricValues(db)
+	require.NoError(t, err)
+	assert.Equal(t, 7, converted)
+	assert.False(t, db.Migrator().HasColumn(&model.ModelMetrics{}, legacyMetricValueColumn))
+
+	var metrics []model.ModelMetrics
+	require.NoError(t, db.Order("step").Find(&metrics).Error)
+	require.Len(t, metrics, 7)
+	assert.Equal(t, 0.5, metrics[0].Float64())
+	assert.Equal(t, 0.5, metrics[1].Float64())
+	assert.Equal(t, 12.0, metrics[2].Float64())
+	assert.True(t, math.IsInf(metrics[3].Float64(), 1))
+	assert.True(t, math.IsNaN(metrics[4].Float64()))
+	assert.True(t, math.IsInf(metrics[5].Float64(), -1))
+	assert.True(t, math.IsNaN(metrics[6].Float64()))
+
+	// Migrating again is a no-op.
+	converted, err = migrateMetricValues(db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, converted)
+}
+
+func TestInsertModelMetrics(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Process{}, &model.ModelMetrics{}))
+
+	// The inserts run through gorm's callbacks, which tracing hooks into.
+	var statements int
+	require.NoError(t, db.Callback().Raw().After("*").Register("test:count", func(*gorm.DB) {
+		statements++
+	}))
+
+	processID := uuid.New()
+	rows := make([]model.ModelMetrics, 2*insertBatchSize+1)
+	for i := range rows {
+		rows[i] = model.ModelMetrics{
+			TenantID: "0", ProcessID: processID, MetricName: "loss", StepName: "step", Step: uint32(i + 1),
+		}
+		if i%2 == 0 {
+			rows[i].MetricValue = sql.NullFloat64{Float64: float64(i), Valid: true}
+		}
+	}
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return insertModelMetrics(tx, rows, nil)
+	}))
+	assert.Equal(t, 3, statements)
+
+	var stored []model.ModelMetrics
+	require.NoError(t, db.Order("step").Find(&stored).Error)
+	require.Len(t, stored, len(rows))
+	for i, m := range stored {
+		assert.Equal(t, rows[i].Step, m.Step)
+		assert.Equal(t, rows[i].MetricValue, m.MetricValue)
+	}
+}
+
+func TestSaveModelMetricsConflictsWithConcurrentRequests(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Process{}, &model.ModelMetrics{}, &model.MetricAxis{}))
+	app := &testApp{App: App{_db: db, dbMux: &sync.Mutex{}, logger: log.NewNopLogger()}}
+
+	process := model.Process{ID: uuid.New(), TenantID: "0"}
+	require.NoError(t, db.Create(&process).Error)
+
+	// Another request saves the point after it was looked up, but before it
+	// is inserted.
+	logged := false
+	require.NoError(t, db.Callback().Query().After("*").Register("test:concurrent", func(tx *gorm.DB) {
+		if logged || tx.Statement.Table != "model_metrics" {
+			return
+		}
+		logged = true
+		metric := createTestMetric(t, "step", 1, "loss", 0.5)
+		metric.TenantID, metric.ProcessID = "0", process.ID
+		require.NoError(t, tx.Session(&gorm.Session{NewDB: true}).Create(&metric).Error)
+	}))
+
+	_, err = app.saveModelMetrics(context.Background(), "0", process.ID, []model.ModelMetrics{createTestMetric(t, "step", 1, "loss", 0.7)}, conflictError)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, middleware.ErrorStatusCode(err))
+}
+
+func TestTransformMetricsDataByTime(t *testing.T) {
+	p1 := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	p2 := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	start1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	start2 := start1.Add(time.Hour)
+	at := func(start time.Time, seconds int) *time.Time {
+		t := start.Add(time.Duration(seconds) * time.Second)
+		return &t
+	}
+	value := func(f float64) *float64 { return &f }
+	results := []Result{
+		{ProcessID: p1, MetricName: "loss", StepName: "step", Step: 1, MetricValue: value(3), Timestamp: at(start1, 10), ProcessStartTime: &start1},
+		{ProcessID: p1, MetricName: "loss", StepName: "step", Step: 2, MetricValue: value(2), Timestamp: at(start1, 20), ProcessStartTime: &start1},
+		{ProcessID: p2, MetricName: "loss", StepName: "step", Step: 1, MetricValue: value(4), Timestamp: at(start2, 10), ProcessStartTime: &start2},
+		{ProcessID: p2, MetricName: "loss", StepName: "step", Step: 2, MetricValue: value(1), Timestamp: at(start2, 15), ProcessStartTime: &start2},
+	}
+
+	response := transformMetricsDataByTime(results, xAxisTime, alignUnion)
+	require.Len(t, response.Sections["default"], 1)
+	assert.Equal(t, DataFrame{
+		{Name: "time", Type: "time", Values: []interface{}{
+			at(start1, 10).UnixMilli(), at(start1, 20).UnixMilli(), at(start2, 10).UnixMilli(), at(start2, 15).UnixMilli(),
+		}},
+		{Name: p1.String(), Type: "number", Values: []interface{}{3.0, 2.0, nil, nil}},
+		{Name: p2.String(), Type: "number", Values: []interface{}{nil, nil, 4.0, 1.0}},
+	}, response.Sections["default"][0].Series)
+
+	// Relative to their start, both processes logged at 10s.
+	response = transformMetricsDataByTime(results, xAxisRelativeTime, alignUnion)
+	assert.Equal(t, DataFrame{
+		{Name: "relative_time", Type: "number", Values: []interface{}{10.0, 15.0, 20.0}},
+		{Name: p1.String(), Type: "number", Values: []interface{}{3.0, nil, 2.0}},
+		{Name: p2.String(), Type: "number", Values: []interface{}{4.0, 1.0, nil}},
+	}, response.Sections["default"][0].Series)
+}
+
+func TestMetricTimestamp(t *testing.T) {
+	var ts MetricTimestamp
+	require.NoError(t, json.Unmarshal([]byte(`"2024-01-01T00:00:00.5Z"`), &ts))
+	assert.True(t, time.Date(2024, 1, 1, 0, 0, 0, 5e8, time.UTC).Equal(time.Time(ts)))
+
+	require.NoError(t, json.Unmarshal([]byte(`1704067200.25`), &ts))
+	assert.True(t, time.Date(2024, 1, 1, 0, 0, 0, 25e7, time.UTC).Equal(time.Time(ts)))
+
+	assert.Error(t, json.Unmarshal([]byte(`"yesterday"`), &ts))
+	assert.Error(t, json.Unmarshal([]byte(`true`), &ts))
+}
+
+// benchmarkDatabases returns the databases to benchmark against: a SQLite
+// file, and MySQL if AI_TRAINING_API_BENCH_MYSQL_ADDR is set to a connection
+// string, e.g. user:password@tcp(localhost:3306)/bench?parseTime=true. They
+// are set up like the app's, as the per-statement overhead of its logging and
+// instrumentation is part of the cost. The rows benchmarks write to MySQL,
+// all of the "bench" tenant, are deleted when the benchmark ends.
+func benchmarkDatabases(b *testing.B) map[string]*gorm.DB {
+	databases := map[string]*gorm.DB{}
+	conn, err := db.New(log.NewNopLogger(), filepath.Join(b.TempDir(), "bench.db"), db.SQLite)
+	require.NoError(b, err)
+	databases[db.SQLite] = conn
+
+	if addr := os.Getenv("AI_TRAINING_API_BENCH_MYSQL_ADDR"); addr != "" {
+		conn, err := db.New(log.NewNopLogger(), addr, db.MySQL)
+		require.NoError(b, err)
+		databases[db.MySQL] = conn
+		b.Cleanup(func() {
+			require.NoError(b, conn.Where("tenant_id = ?", "bench").Delete(&model.ModelMetrics{}).Error)
+			require.NoError(b, conn.Where("tenant_id = ?", "bench").Delete(&model.Process{}).Error)
+		})
+	} else {
+		b.Log("AI_TRAINING_API_BENCH_MYSQL_ADDR is not set, only SQLite is benchmarked")
+	}
+
+	for _, conn := range databases {
+		require.NoError(b, conn.AutoMigrate(&model.Process{}, &model.ModelMetrics{}))
+	}
+	return databases
+}
+
+// BenchmarkInsertModelMetrics compares inserting a 10k point request one
+// row per statement, as metrics used to be saved, with inserting it with
+// insertModelMetrics, e.g.
+//
+//	go test ./app -run '^$' -bench InsertModelMetrics
+func BenchmarkInsertModelMetrics(b *testing.B) {
+	const points = 10000
+	inserts := []struct {
+		name   string
+		insert func(tx *gorm.DB, rows []model.ModelMetrics) error
+	}{
+		{"row_by_row", func(tx *gorm.DB, rows []model.ModelMetrics) error {
+			for _, row := range rows {
+				if err := tx.Create(&row).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"batched", func(tx *gorm.DB, rows []model.ModelMetrics) error {
+			return insertModelMetrics(tx, rows, nil)
+		}},
+	}
+
+	for dbType, conn := range benchmarkDatabases(b) {
+		for _, tt := range inserts {
+			b.Run(dbType+"/"+tt.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					processID := uuid.New()
+					require.NoError(b, conn.Create(&model.Process{ID: processID, TenantID: "bench"}).Error)
+					rows := make([]model.ModelMetrics, 0, points)
+					for step := 1; step <= points; step++ {
+						row := model.ModelMetrics{
+							TenantID:   "bench",
+							ProcessID:  processID,
+							MetricName: "loss",
+							StepName:   "step",
+							Step:       uint32(step),
+						}
+						row.SetValue(1 / float64(step))
+						rows = append(rows, row)
+					}
+					b.StartTimer()
+
+					err := conn.Transaction(func(tx *gorm.DB) error {
+						return tt.insert(tx, rows)
+					})
+					require.NoError(b, err)
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*points), "ns/point")
+			})
+		}
+	}
+}
+
+// transformMetricsData turns results into one panel per metric and step
+// name, plotted against the step, like the rows of crossJoinStepMetrics used
+// to be.
+func transformMetricsData(results []Result, mode string) GetModelMetricsResponse {
+	return transformStepSeries(seriesOf(results, func(r Result) (float64, bool) {
+		return float64(r.Step), true
+	}), mode)
+}
+
+// crossJoinStepMetrics is how the metrics of processes plotted against their
+// steps used to be read: the query padded every series with a row at every
+// step of its panel, by joining the series with the steps.
+func crossJoinStepMetrics(ctx context.Context, db *gorm.DB, tenantID string, processIDs []uuid.UUID) ([]Result, error) {
+	var results []Result
+	err := db.WithContext(ctx).Raw(`
+		WITH process_metrics AS (
+			SELECT DISTINCT process_id, metric_name, step_name
+			FROM model_metrics
+			WHERE tenant_id = ? AND process_id IN ?
+		),
+		metric_steps AS (
+			SELECT metric_name, step_name, step
+			FROM model_metrics
+			WHERE tenant_id = ? AND process_id IN ?
+		),
+		all_combinations AS (
+			SELECT DISTINCT pm.process_id, pm.metric_name, pm.step_name, ms.step
+			FROM process_metrics pm
+			JOIN metric_steps ms ON pm.metric_name = ms.metric_name AND pm.step_name = ms.step_name
+		)
+		SELECT DISTINCT ac.process_id, ac.metric_name, ac.step_name, ac.step, d.metric_value, d.non_finite
+		FROM all_combinations ac
+		LEFT JOIN model_metrics d ON d.tenant_id = ? AND d.process_id = ac.process_id
+			AND d.metric_name = ac.metric_name AND d.step_name = ac.step_name AND d.step = ac.step
+		ORDER BY ac.step ASC`,
+		tenantID, processIDs, tenantID, processIDs, tenantID).Scan(&results).Error
+	return results, err
+}
+
+// BenchmarkGetStepMetrics compares reading the loss of 50 runs of 100k steps
+// each, plotted against their steps, with the cross join they used to be
+// read with, and with each series read once and aligned in Go in each
+// alignment mode, on SQLite and on MySQL, e.g.
+//
+//	AI_TRAINING_API_BENCH_MYSQL_ADDR='user:password@tcp(localhost:3306)/bench?parseTime=true' \
+//		go test ./app -run '^$' -bench GetStepMetrics -benchtime 1x -timeout 2h
+func BenchmarkGetStepMetrics(b *testing.B) {
+	const (
+		runs  = 50
+		steps = 100000
+	)
+	type stepRead func(ctx context.Context, db *gorm.DB, processIDs []uuid.UUID) (GetModelMetricsResponse, error)
+	reads := map[string]stepRead{
+		"cross_join": func(ctx context.Context, db *gorm.DB, processIDs []uuid.UUID) (GetModelMetricsResponse, error) {
+			results, err := crossJoinStepMetrics(ctx, db, "bench", processIDs)
+			if err != nil {
+				return GetModelMetricsResponse{}, err
+			}
+			return transformMetricsData(results, alignUnion), nil
+		},
+	}
+	for _, mode := range []string{alignUnion, alignIntersection, alignInterpolate} {
+		mode := mode
+		reads[mode] = func(ctx context.Context, db *gorm.DB, processIDs []uuid.UUID) (GetModelMetricsResponse, error) {
+			series, err := getStepSeries(ctx, db, "bench", ModelMetricsQuery{ProcessIDs: processIDs})
+			if err != nil {
+				return GetModelMetricsResponse{}, err
+			}
+			return transformStepSeries(series, mode), nil
+		}
+	}
+
+	for dbType, conn := range benchmarkDatabases(b) {
+		// Runs are evaluated at different steps: each logs its loss at
+		// every step from a different first step.
+		processIDs := make([]uuid.UUID, runs)
+		for i := range processIDs {
+			processIDs[i] = uuid.New()
+			require.NoError(b, conn.Create(&model.Process{ID: processIDs[i], TenantID: "bench"}).Error)
+			rows := make([]model.ModelMetrics, 0, steps)
+			for step := i + 1; step <= steps+i; step++ {
+				row := model.ModelMetrics{
+					TenantID:   "bench",
+					ProcessID:  processIDs[i],
+					MetricName: "loss",
+					StepName:   "step",
+					Step:       uint32(step),
+				}
+				row.SetValue(1 / float64(step))
+				rows = append(rows, row)
+			}
+			require.NoError(b, conn.Transaction(func(tx *gorm.DB) error {
+				return insertInBatches(tx, rows, insertBatchSize)
+			}))
+		}
+
+		for name, read := range reads {
+			b.Run(dbType+"/"+name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					response, err := read(context.Background(), conn, processIDs)
+					require.NoError(b, err)
+					require.Len(b, response.Sections[defaultSection], 1)
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*runs*steps), "ns/point")
+			})
+		}
+	}
+}
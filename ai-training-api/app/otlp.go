@@ -0,0 +1,314 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/grafana/dskit/user"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+)
+
+// OTLPConfig are the attribute conventions that map OTLP metrics to model
+// metrics.
+type OTLPConfig struct {
+	// ProcessAttribute is the resource attribute holding the ID of the
+	// process. Metrics of other resources are skipped.
+	ProcessAttribute string
+	// StepAttribute is the data point attribute holding the step. It is
+	// also the step name the points are logged under.
+	StepAttribute string
+	// SectionAttribute, if set, is a data point attribute whose value is the
+	// section of the metric: points with it are logged as section/name, so
+	// that points differing only by it, e.g. the loss of the train and eval
+	// phases, are kept apart.
+	SectionAttribute string
+}
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// otlpMetrics receives OTLP/HTTP metrics export requests, encoded either as
+// protobuf or JSON. The data points of gauges and sums of the resources
+// carrying a process ID are logged as model metrics; other data points are
+// reported as rejected in the partial success of the response. Attributes
+// other than the configured ones don't tell points apart, so of the data
+// points of a request that map to the same point only those of the first
+// attribute set are kept, and the others are rejected. Points that were
+// already logged are overwritten, since exporters retry failed requests and
+// cumulative sums are sent again at each export.
+//
+// Responses follow the OTLP specification rather than the conventions of the
+// rest of the API, which SDKs wouldn't understand.
+func (a *App) otlpMetrics(w http.ResponseWriter, req *http.Request) {
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if contentType != contentTypeProtobuf && contentType != contentTypeJSON {
+		// OTLP clients that send neither don't understand the responses.
+		http.Error(w, fmt.Sprintf("unsupported Content-Type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	resp, err := a.exportOTLPMetrics(req, contentType)
+	if err != nil {
+		code := middleware.ErrorStatusCode(err)
+		level.Error(a.logger).Log("msg", "Error in OTLP request", "err", err, "code", code)
+		writeOTLPResponse(w, contentType, code, status.New(otlpStatusCode(code), err.Error()).Proto())
+		return
+	}
+	writeOTLPResponse(w, contentType, http.StatusOK, resp)
+}
+
+func (a *App) exportOTLPMetrics(req *http.Request, contentType string) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	tenantID, err := user.ExtractOrgID(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	defer func() {
+		a.ingestDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	body, err := metricsRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	raw, err := io.ReadAll(http.MaxBytesReader(nil, io.NopCloser(body), a.maxMetricsPayloadBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, middleware.ErrPayloadTooLarge(fmt.Errorf("request body is larger than %d bytes", tooLarge.Limit))
+		}
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	export := &colmetricspb.ExportMetricsServiceRequest{}
+	if contentType == contentTypeJSON {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(raw, export)
+	} else {
+		err = proto.Unmarshal(raw, export)
+	}
+	if err != nil {
+		return nil, middleware.ErrBadRequest(fmt.Errorf("invalid export request: %w", err))
+	}
+
+	metricsByProcess, rejected := metricsFromOTLP(a.otlpConfig, export, time.Now())
+	_, unknown, err := a.saveProcessesMetrics(req.Context(), tenantID, metricsByProcess, conflictOverwrite)
+	if err != nil {
+		return nil, err
+	}
+	rejected += unknown
+
+	resp := &colmetricspb.ExportMetricsServiceResponse{}
+	if rejected > 0 {
+		resp.PartialSuccess = &colmetricspb.ExportMetricsPartialSuccess{
+			RejectedDataPoints: int64(rejected),
+			ErrorMessage: fmt.Sprintf("only gauge and sum data points with a step attribute %q, of resources with a %q attribute holding the ID of an existing process, are accepted, and of data points differing only by other attributes the first",
+				a.otlpConfig.StepAttribute, a.otlpConfig.ProcessAttribute),
+		}
+	}
+	return resp, nil
+}
+
+// writeOTLPResponse writes a response message, or the Status of an error,
+// with the encoding of the request.
+func writeOTLPResponse(w http.ResponseWriter, contentType string, code int, msg proto.Message) {
+	var body []byte
+	var err error
+	if contentType == contentTypeJSON {
+		body, err = protojson.Marshal(msg)
+	} else {
+		body, err = proto.Marshal(msg)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	//nolint:errcheck // Just do our best to write.
+	w.Write(body)
+}
+
+// otlpStatusCode returns the gRPC code of the Status of an OTLP error
+// response.
+func otlpStatusCode(httpCode int) codes.Code {
+	switch httpCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusRequestEntityTooLarge:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+// metricsFromOTLP maps the data points of an export request to the points of
+// each process. It returns how many data points were rejected.
+func metricsFromOTLP(cfg OTLPConfig, export *colmetricspb.ExportMetricsServiceRequest, receivedAt time.Time) (map[uuid.UUID][]model.ModelMetrics, int) {
+	type pointKey struct {
+		processID  uuid.UUID
+		metricName string
+		step       uint32
+	}
+	// owners are the attributes of the data points each point was first
+	// mapped from.
+	owners := map[pointKey]string{}
+	metricsByProcess := map[uuid.UUID][]model.ModelMetrics{}
+	rejected := 0
+	for _, rm := range export.GetResourceMetrics() {
+		processID, processErr := uuid.Parse(stringAttribute(rm.GetResource().GetAttributes(), cfg.ProcessAttribute))
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				var points []*metricspb.NumberDataPoint
+				switch {
+				case m.GetGauge() != nil:
+					points = m.GetGauge().GetDataPoints()
+				case m.GetSum() != nil:
+					points = m.GetSum().GetDataPoints()
+				default:
+					rejected += countDataPoints(m)
+					continue
+				}
+				if processErr != nil {
+					rejected += len(points)
+					continue
+				}
+
+				for _, p := range points {
+					metric, ok := modelMetricFromDataPoint(cfg, m.GetName(), p, receivedAt)
+					if !ok {
+						rejected++
+						continue
+					}
+					key := pointKey{processID: processID, metricName: metric.MetricName, step: metric.Step}
+					attributes := attributeSet(p.GetAttributes(), cfg.StepAttribute)
+					if owner, ok := owners[key]; ok && owner != attributes {
+						rejected++
+						continue
+					}
+					owners[key] = attributes
+					metricsByProcess[processID] = append(metricsByProcess[processID], metric)
+				}
+			}
+		}
+	}
+	return metricsByProcess, rejected
+}
+
+func modelMetricFromDataPoint(cfg OTLPConfig, name string, p *metricspb.NumberDataPoint, receivedAt time.Time) (model.ModelMetrics, bool) {
+	if p.GetFlags()&uint32(metricspb.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0 {
+		return model.ModelMetrics{}, false
+	}
+	step, ok := stepFromAttribute(p.GetAttributes(), cfg.StepAttribute)
+	if !ok {
+		return model.ModelMetrics{}, false
+	}
+
+	if section := stringAttribute(p.GetAttributes(), cfg.SectionAttribute); cfg.SectionAttribute != "" && section != "" {
+		name = section + "/" + name
+	}
+
+	timestamp := receivedAt
+	if p.GetTimeUnixNano() != 0 {
+		timestamp = time.Unix(0, int64(p.GetTimeUnixNano()))
+	}
+	metric := model.ModelMetrics{
+		MetricName: name,
+		StepName:   cfg.StepAttribute,
+		Step:       step,
+		Timestamp:  sql.NullTime{Time: timestamp, Valid: true},
+	}
+	switch v := p.GetValue().(type) {
+	case *metricspb.NumberDataPoint_AsDouble:
+		metric.SetValue(v.AsDouble)
+	case *metricspb.NumberDataPoint_AsInt:
+		metric.SetValue(float64(v.AsInt))
+	default:
+		return model.ModelMetrics{}, false
+	}
+	if validateModelMetric(&metric) != nil {
+		return model.ModelMetrics{}, false
+	}
+	return metric, true
+}
+
+// stepFromAttribute returns the step held by an attribute, either as an
+// integer, an integral double or a string.
+func stepFromAttribute(attributes []*commonpb.KeyValue, key string) (uint32, bool) {
+	for _, kv := range attributes {
+		if kv.GetKey() != key {
+			continue
+		}
+		switch v := kv.GetValue().GetValue().(type) {
+		case *commonpb.AnyValue_IntValue:
+			if v.IntValue < 0 || v.IntValue > math.MaxUint32 {
+				return 0, false
+			}
+			return uint32(v.IntValue), true
+		case *commonpb.AnyValue_DoubleValue:
+			return stepFromFloat(v.DoubleValue)
+		case *commonpb.AnyValue_StringValue:
+			return stepFromLabel(v.StringValue)
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// attributeSet returns an encoding of the attributes other than except that
+// is the same for the same attributes in any order.
+func attributeSet(attributes []*commonpb.KeyValue, except string) string {
+	kept := make([]*commonpb.KeyValue, 0, len(attributes))
+	for _, kv := range attributes {
+		if kv.GetKey() != except {
+			kept = append(kept, kv)
+		}
+	}
+	slices.SortFunc(kept, func(a, b *commonpb.KeyValue) int { return strings.Compare(a.GetKey(), b.GetKey()) })
+	// Attributes that were unmarshaled can be marshaled again.
+	encoded, _ := proto.MarshalOptions{Deterministic: true}.Marshal(&commonpb.KeyValueList{Values: kept})
+	return string(encoded)
+}
+
+func stringAttribute(attributes []*commonpb.KeyValue, key string) string {
+	for _, kv := range attributes {
+		if kv.GetKey() == key {
+			return kv.GetValue().GetStringValue()
+		}
+	}
+	return ""
+}
+
+func countDataPoints(m *metricspb.Metric) int {
+	switch {
+	case m.GetHistogram() != nil:
+		return len(m.GetHistogram().GetDataPoints())
+	case m.GetExponentialHistogram() != nil:
+		return len(m.GetExponentialHistogram().GetDataPoints())
+	case m.GetSummary() != nil:
+		return len(m.GetSummary().GetDataPoints())
+	}
+	return 0
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func stringKV(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func intKV(key string, value int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}}}
+}
+
+func TestAppReceivesOTLPMetrics(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	serverURL := "http://" + testApp.server.HTTPListenAddr().String()
+	resp, err := httpC.Post(serverURL+"/api/v1/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+	processID := cpr.Data.ID.String()
+
+	now := uint64(time.Now().UnixNano())
+	export := &colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: []*metricspb.ResourceMetrics{
+		{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{stringKV("service.name", "trainer"), stringKV("process_id", processID)}},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: []*metricspb.Metric{
+				{Name: "loss", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: []*metricspb.NumberDataPoint{
+					{Attributes: []*commonpb.KeyValue{intKV("step", 1)}, TimeUnixNano: now, Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 2}},
+					{Attributes: []*commonpb.KeyValue{stringKV("step", "2")}, TimeUnixNano: now, Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 1}},
+					// No step.
+					{TimeUnixNano: now, Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 1}},
+				}}}},
+				{Name: "tokens", Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{IsMonotonic: true, DataPoints: []*metricspb.NumberDataPoint{
+					{Attributes: []*commonpb.KeyValue{intKV("step", 1)}, TimeUnixNano: now, Value: &metricspb.NumberDataPoint_AsInt{AsInt: 4096}},
+				}}}},
+				{Name: "grad_norm", Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{DataPoints: []*metricspb.HistogramDataPoint{
+					{Attributes: []*commonpb.KeyValue{intKV("step", 1)}, TimeUnixNano: now, Count: 1},
+				}}}},
+			}}},
+		},
+		{
+			// Not a training process.
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{stringKV("service.name", "loader")}},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: []*metricspb.Metric{
+				{Name: "queue_size", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: []*metricspb.NumberDataPoint{
+					{Attributes: []*commonpb.KeyValue{intKV("step", 1)}, TimeUnixNano: now, Value: &metricspb.NumberDataPoint_AsInt{AsInt: 3}},
+				}}}},
+			}}},
+		},
+	}}
+	body, err := proto.Marshal(export)
+	require.NoError(t, err)
+	resp, err = httpC.Post(serverURL+"/v1/metrics", "application/x-protobuf", bytes.NewReader(body))
+	require.NoError(t, err)
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(respBody))
+	assert.Equal(t, "application/x-protobuf", resp.Header.Get("Content-Type"))
+	exportResp := &colmetricspb.ExportMetricsServiceResponse{}
+	require.NoError(t, proto.Unmarshal(respBody, exportResp))
+	assert.Equal(t, int64(3), exportResp.GetPartialSuccess().GetRejectedDataPoints())
+
+	// JSON requests get JSON responses, without partial success when all
+	// data points are accepted.
+	jsonBody := `{"resourceMetrics": [{
+		"resource": {"attributes": [{"key": "process_id", "value": {"stringValue": "` + processID + `"}}]},
+		"scopeMetrics": [{"metrics": [{"name": "accuracy", "gauge": {"dataPoints": [
+			{"attributes": [{"key": "step", "value": {"intValue": "3"}}], "timeUnixNano": "1700000000000000000", "asDouble": 0.5}
+		]}}]}]
+	}]}`
+	resp, err = httpC.Post(serverURL+"/v1/metrics", "application/json", bytes.NewBufferString(jsonBody))
+	require.NoError(t, err)
+	respBody, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(respBody))
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{}`, string(respBody))
+
+	resp, err = httpC.Post(serverURL+"/v1/metrics", "application/json", bytes.NewBufferString(`{"resourceMetrics": 1}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = httpC.Post(serverURL+"/v1/metrics", "text/plain", bytes.NewBufferString(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = httpC.Post(serverURL+"/api/v1/processes/model-metrics", "application/json", bytes.NewBufferString(`["`+processID+`"]`))
+	require.NoError(t, err)
+	gmr := read[getModelMetricsResponse](t, resp)
+	panels := map[string][]interface{}{}
+	for _, panel := range gmr.Data.Sections["default"] {
+		panels[panel.Title] = panel.Series[1].Values
+	}
+	assert.Equal(t, map[string][]interface{}{
+		"loss":     {float64(2), float64(1)},
+		"tokens":   {float64(4096)},
+		"accuracy": {0.5},
+	}, panels)
+
+	metrics, rejected := metricsFromOTLP(testApp.otlpConfig, &colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: []*metricspb.ResourceMetrics{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{stringKV("process_id", uuid.NewString())}},
+		ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: []*metricspb.Metric{
+			{Name: "loss", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: []*metricspb.NumberDataPoint{
+				{Attributes: []*commonpb.KeyValue{intKV("step", -1)}, Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 1}},
+				{Attributes: []*commonpb.KeyValue{intKV("step", 1)}, Flags: uint32(metricspb.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK)},
+			}}}},
+		}}},
+	}}}, time.Now())
+	assert.Empty(t, metrics)
+	assert.Equal(t, 2, rejected)
+
+	// Data points differing only by other attributes than the step and the
+	// section map to the same points, only the first ones are kept.
+	processUUID := uuid.New()
+	cfg := OTLPConfig{ProcessAttribute: "process_id", StepAttribute: "step", SectionAttribute: "phase"}
+	metrics, rejected = metricsFromOTLP(cfg, &colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: []*metricspb.ResourceMetrics{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{stringKV("process_id", processUUID.String())}},
+		ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: []*metricspb.Metric{
+			{Name: "loss", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: []*metricspb.NumberDataPoint{
+				{Attributes: []*commonpb.KeyValue{intKV("step", 1), stringKV("phase", "train"), intKV("rank", 0)}, Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 1}},
+				{Attributes: []*commonpb.KeyValue{intKV("step", 1), stringKV("phase", "eval"), intKV("rank", 0)}, Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 2}},
+				{Attributes: []*commonpb.KeyValue{intKV("step", 1), stringKV("phase", "train"), intKV("rank", 1)}, Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 3}},
+				{Attributes: []*commonpb.KeyValue{intKV("rank", 1), intKV("step", 2), stringKV("phase", "train")}, Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 4}},
+				// The same attributes as the first point, in another order.
+				{Attributes: []*commonpb.KeyValue{intKV("rank", 0), stringKV("phase", "train"), intKV("step", 1)}, Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 5}},
+			}}}},
+		}}},
+	}}}, time.Now())
+	assert.Equal(t, 1, rejected)
+	var points []string
+	for _, m := range metrics[processUUID] {
+		points = append(points, fmt.Sprintf("%s@%d=%v", m.MetricName, m.Step, m.Float64()))
+	}
+	assert.Equal(t, []string{"train/loss@1=1", "eval/loss@1=2", "train/loss@2=4", "train/loss@1=5"}, points)
+}
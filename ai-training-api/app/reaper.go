@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log/level"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+)
+
+const (
+	// reaperInterval is how often the reaper looks for stale processes.
+	reaperInterval = time.Minute
+	// reaperExitReason is recorded on processes marked as crashed by the reaper.
+	reaperExitReason = "heartbeat_timeout"
+)
+
+// heartbeatTimeout returns the heartbeat timeout for the given tenant. A zero
+// timeout disables reaping for that tenant.
+func (a *App) heartbeatTimeout(tenantID string) time.Duration {
+	if timeout, ok := a.tenantHeartbeatTimeouts[tenantID]; ok {
+		return timeout
+	}
+	return a.defaultHeartbeatTimeout
+}
+
+// minHeartbeatTimeout returns the smallest enabled heartbeat timeout across
+// all tenants, or zero if reaping is disabled for everyone. Processes that have
+// been seen more recently than this cannot be stale for any tenant.
+func (a *App) minHeartbeatTimeout() time.Duration {
+	var min time.Duration
+	for _, timeout := range a.tenantHeartbeatTimeouts {
+		if timeout > 0 && (min == 0 || timeout < min) {
+			min = timeout
+		}
+	}
+	if a.defaultHeartbeatTimeout > 0 && (min == 0 || a.defaultHeartbeatTimeout < min) {
+		min = a.defaultHeartbeatTimeout
+	}
+	return min
+}
+
+// runReaper periodically marks processes whose heartbeat has gone stale as
+// crashed. It returns when the context is cancelled.
+func (a *App) runReaper(ctx context.Context) {
+	ticker := time.NewTicker(reaperInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			reaped, err := a.reapStaleProcesses(ctx, now)
+			if err != nil {
+				level.Error(a.logger).Log("msg", "error reaping stale processes", "err", err)
+				continue
+			}
+			if reaped > 0 {
+				level.Info(a.logger).Log("msg", "reaped stale processes", "count", reaped)
+			}
+		}
+	}
+}
+
+// reapStaleProcesses marks every running process that has not been seen for
+// longer than its tenant's heartbeat timeout as crashed. The end time of a
+// crashed process is the last time it was seen alive.
+func (a *App) reapStaleProcesses(ctx context.Context, now time.Time) (int, error) {
+	minTimeout := a.minHeartbeatTimeout()
+	if minTimeout <= 0 {
+		return 0, nil
+	}
+	cutoff := now.Add(-minTimeout)
+
+	var candidates []model.Process
+	err := a.db(ctx).
+		Where("status = ? AND start_time < ?", model.ProcessStatusRunning, cutoff).
+		Where("last_heartbeat_at IS NULL OR last_heartbeat_at < ?", cutoff).
+		Find(&candidates).Error
+	if err != nil {
+		return 0, err
+	}
+
+	reaped := 0
+	for _, process := range candidates {
+		timeout := a.heartbeatTimeout(process.TenantID)
+		if timeout <= 0 {
+			continue
+		}
+		tenantCutoff := now.Add(-timeout)
+		lastSeen := process.LastSeen()
+		if !lastSeen.Before(tenantCutoff) {
+			continue
+		}
+
+		if err := process.Transition(model.ProcessStatusCrashed, lastSeen); err != nil {
+			return reaped, err
+		}
+		process.ExitReason = reaperExitReason
+
+		// Only update processes that are still running, in case the process
+		// reported a state or a heartbeat since we read it.
+		result := a.db(ctx).Model(&model.Process{}).
+			Where("id = ? AND status = ?", process.ID, model.ProcessStatusRunning).
+			Where("last_heartbeat_at IS NULL OR last_heartbeat_at < ?", tenantCutoff).
+			Updates(map[string]interface{}{
+				"status":      process.Status,
+				"end_time":    process.EndTime,
+				"exit_reason": process.ExitReason,
+			})
+		if result.Error != nil {
+			return reaped, result.Error
+		}
+		if result.RowsAffected > 0 {
+			level.Debug(a.logger).Log("msg", "marked stale process as crashed", "tenantID", process.TenantID, "process_id", process.ID, "last_seen", lastSeen)
+			reaped++
+		}
+	}
+
+	return reaped, nil
+}
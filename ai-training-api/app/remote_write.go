@@ -0,0 +1,250 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+	"github.com/grafana/ai-training-o11y/ai-training-api/prompb"
+)
+
+// RemoteWriteConfig are the label conventions that map the series of
+// Prometheus remote-write requests to model metrics.
+type RemoteWriteConfig struct {
+	// ProcessLabel is the label holding the ID of the process. Series
+	// without it are not training metrics and are skipped.
+	ProcessLabel string
+	// MetricLabel is the label holding the metric name, usually __name__.
+	MetricLabel string
+	// MetricPrefix, if set, is trimmed from metric names. Series whose name
+	// doesn't start with it are skipped, e.g. the runtime metrics client
+	// libraries export.
+	MetricPrefix string
+	// SectionLabel, if set, is a label whose value is the section of the
+	// metric: series with it are logged as section/name, so that series
+	// differing only by it, e.g. the loss of the train and eval phases, are
+	// kept apart.
+	SectionLabel string
+	// StepLabel is the label holding the step of the samples of a series.
+	StepLabel string
+	// StepSeries, if set, is the metric name of a series of each process
+	// whose value is the current step. The samples of the other series of
+	// the process are logged at the step of the latest sample of this series
+	// at or before them, instead of reading StepLabel.
+	StepSeries string
+	// StepName is the step name the points are logged under.
+	StepName string
+}
+
+// staleNaN is the value Prometheus marks series that went away with.
+const staleNaN = 0x7ff0000000000002
+
+// remoteWrite receives Prometheus remote-write requests, a snappy
+// compressed WriteRequest. Series are mapped to model metrics following the
+// label conventions of the RemoteWriteConfig; samples that can't be mapped
+// are skipped and counted. Labels other than the configured ones don't tell
+// points apart, so of the series of a request that map to the same points only
+// the first is kept and the others are skipped. Points that were already
+// logged are overwritten, since remote-write clients retry failed requests as
+// is.
+func (a *App) remoteWrite(tenantID string, req *http.Request) (interface{}, error) {
+	start := time.Now()
+	defer func() {
+		a.ingestDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	writeRequest, err := a.readWriteRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	metricsByProcess, skipped := metricsFromWriteRequest(a.remoteWriteConfig, writeRequest, time.Now())
+	result, unknown, err := a.saveProcessesMetrics(req.Context(), tenantID, metricsByProcess, conflictOverwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"message":            "Metrics successfully added",
+		"metricsCreated":     result.Created,
+		"metricsOverwritten": result.Overwritten,
+		"samplesSkipped":     skipped + unknown,
+	}, nil
+}
+
+func (a *App) readWriteRequest(req *http.Request) (*prompb.WriteRequest, error) {
+	compressed, err := io.ReadAll(http.MaxBytesReader(nil, req.Body, a.maxMetricsPayloadBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, middleware.ErrPayloadTooLarge(fmt.Errorf("request body is larger than %d bytes", tooLarge.Limit))
+		}
+		return nil, middleware.ErrBadRequest(err)
+	}
+
+	size, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(fmt.Errorf("invalid snappy body: %w", err))
+	}
+	if int64(size) > a.maxMetricsPayloadBytes {
+		return nil, middleware.ErrPayloadTooLarge(fmt.Errorf("decompressed request body is larger than %d bytes", a.maxMetricsPayloadBytes))
+	}
+	body, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, middleware.ErrBadRequest(fmt.Errorf("invalid snappy body: %w", err))
+	}
+
+	writeRequest := &prompb.WriteRequest{}
+	if err := proto.Unmarshal(body, writeRequest); err != nil {
+		return nil, middleware.ErrBadRequest(fmt.Errorf("invalid write request: %w", err))
+	}
+	return writeRequest, nil
+}
+
+// metricsFromWriteRequest maps the samples of a write request to the points
+// of each process. It returns how many samples were skipped, either because
+// their series doesn't follow the label conventions, because they aren't
+// valid points or because an earlier series of the request maps to the same
+// point.
+func metricsFromWriteRequest(cfg RemoteWriteConfig, writeRequest *prompb.WriteRequest, receivedAt time.Time) (map[uuid.UUID][]model.ModelMetrics, int) {
+	type series struct {
+		processID  uuid.UUID
+		metricName string
+		labels     map[string]string
+		samples    []*prompb.Sample
+	}
+
+	skipped := 0
+	var training []series
+	steps := map[uuid.UUID]stepTimeline{}
+	for _, ts := range writeRequest.GetTimeseries() {
+		labels := make(map[string]string, len(ts.GetLabels()))
+		for _, l := range ts.GetLabels() {
+			labels[l.GetName()] = l.GetValue()
+		}
+
+		processID, err := uuid.Parse(labels[cfg.ProcessLabel])
+		name, ok := strings.CutPrefix(labels[cfg.MetricLabel], cfg.MetricPrefix)
+		if err != nil || !ok {
+			skipped += len(ts.GetSamples())
+			continue
+		}
+
+		if cfg.StepSeries != "" && name == cfg.StepSeries {
+			for _, s := range ts.GetSamples() {
+				step, ok := stepFromFloat(s.GetValue())
+				if !ok {
+					skipped++
+					continue
+				}
+				steps[processID] = append(steps[processID], stepAt{timestamp: s.GetTimestamp(), step: step})
+			}
+			continue
+		}
+		if section := labels[cfg.SectionLabel]; cfg.SectionLabel != "" && section != "" {
+			name = section + "/" + name
+		}
+		training = append(training, series{processID: processID, metricName: name, labels: labels, samples: ts.GetSamples()})
+	}
+	for _, timeline := range steps {
+		sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].timestamp < timeline[j].timestamp })
+	}
+
+	type pointKey struct {
+		processID  uuid.UUID
+		metricName string
+		step       uint32
+	}
+	// owners are the series each point was first mapped from.
+	owners := map[pointKey]int{}
+	metricsByProcess := map[uuid.UUID][]model.ModelMetrics{}
+	for i, s := range training {
+		for _, sample := range s.samples {
+			if math.Float64bits(sample.GetValue()) == staleNaN {
+				skipped++
+				continue
+			}
+
+			var step uint32
+			var ok bool
+			if cfg.StepSeries != "" {
+				step, ok = steps[s.processID].at(sample.GetTimestamp())
+			} else {
+				step, ok = stepFromLabel(s.labels[cfg.StepLabel])
+			}
+			if !ok {
+				skipped++
+				continue
+			}
+
+			timestamp := receivedAt
+			if sample.GetTimestamp() != 0 {
+				timestamp = time.UnixMilli(sample.GetTimestamp())
+			}
+			metric := model.ModelMetrics{
+				MetricName: s.metricName,
+				StepName:   cfg.StepName,
+				Step:       step,
+				Timestamp:  sql.NullTime{Time: timestamp, Valid: true},
+			}
+			metric.SetValue(sample.GetValue())
+			if validateModelMetric(&metric) != nil {
+				skipped++
+				continue
+			}
+			key := pointKey{processID: s.processID, metricName: s.metricName, step: step}
+			if owner, ok := owners[key]; ok && owner != i {
+				skipped++
+				continue
+			}
+			owners[key] = i
+			metricsByProcess[s.processID] = append(metricsByProcess[s.processID], metric)
+		}
+	}
+	return metricsByProcess, skipped
+}
+
+// stepAt is a sample of the step series of a process.
+type stepAt struct {
+	timestamp int64
+	step      uint32
+}
+
+// stepTimeline are the samples of the step series of a process, sorted by
+// timestamp.
+type stepTimeline []stepAt
+
+// at returns the step at a timestamp, the one of the latest sample at or
+// before it.
+func (t stepTimeline) at(timestamp int64) (uint32, bool) {
+	i := sort.Search(len(t), func(i int) bool { return t[i].timestamp > timestamp })
+	if i == 0 {
+		return 0, false
+	}
+	return t[i-1].step, true
+}
+
+func stepFromLabel(value string) (uint32, bool) {
+	step, err := strconv.ParseUint(value, 10, 32)
+	return uint32(step), err == nil
+}
+
+func stepFromFloat(value float64) (uint32, bool) {
+	if value < 0 || value > math.MaxUint32 || value != math.Trunc(value) {
+		return 0, false
+	}
+	return uint32(value), true
+}
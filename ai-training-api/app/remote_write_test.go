@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang/snappy"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/middleware"
+	"github.com/grafana/ai-training-o11y/ai-training-api/prompb"
+)
+
+func series(samples []*prompb.Sample, labels ...string) *prompb.TimeSeries {
+	ts := &prompb.TimeSeries{Samples: samples}
+	for i := 0; i < len(labels); i += 2 {
+		ts.Labels = append(ts.Labels, &prompb.Label{Name: labels[i], Value: labels[i+1]})
+	}
+	return ts
+}
+
+func TestAppReceivesPrometheusRemoteWrite(t *testing.T) {
+	logger := log.NewNopLogger()
+	testApp := NewTestApp(t, logger)
+	require.NotNil(t, testApp)
+	defer testApp.Shutdown()
+
+	httpC := newHTTPClient(t.Name())
+	baseURL := "http://" + testApp.server.HTTPListenAddr().String() + "/api/v1"
+	resp, err := httpC.Post(baseURL+"/process/new", "application/json", bytes.NewBufferString(sampleProcessNestedJSON))
+	require.NoError(t, err)
+	cpr := read[createProcessResponse](t, resp)
+	processID := cpr.Data.ID.String()
+
+	type writeResponse struct {
+		middleware.ResponseWrapper
+		Data struct {
+			MetricsCreated     int `json:"metricsCreated"`
+			MetricsOverwritten int `json:"metricsOverwritten"`
+			SamplesSkipped     int `json:"samplesSkipped"`
+		} `json:"data"`
+	}
+	write := func(timeseries ...*prompb.TimeSeries) *http.Response {
+		body, err := proto.Marshal(&prompb.WriteRequest{Timeseries: timeseries})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, baseURL+"/write", bytes.NewReader(snappy.Encode(nil, body)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("Content-Encoding", "snappy")
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+		resp, err := httpC.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	now := time.Now().UnixMilli()
+	timeseries := []*prompb.TimeSeries{
+		series([]*prompb.Sample{{Value: 2, Timestamp: now}}, "__name__", "loss", "process_id", processID, "step", "1"),
+		series([]*prompb.Sample{{Value: 1, Timestamp: now + 1000}}, "__name__", "loss", "process_id", processID, "step", "2"),
+		series([]*prompb.Sample{{Value: math.Float64frombits(staleNaN), Timestamp: now + 2000}}, "__name__", "loss", "process_id", processID, "step", "3"),
+		// Not training metrics, or not following the conventions.
+		series([]*prompb.Sample{{Value: 12, Timestamp: now}}, "__name__", "go_goroutines", "job", "trainer"),
+		series([]*prompb.Sample{{Value: 1, Timestamp: now}}, "__name__", "loss", "process_id", processID),
+		series([]*prompb.Sample{{Value: 1, Timestamp: now}}, "__name__", "loss", "process_id", uuid.NewString(), "step", "1"),
+	}
+	wr := read[writeResponse](t, write(timeseries...))
+	assert.Equal(t, 2, wr.Data.MetricsCreated)
+	assert.Equal(t, 4, wr.Data.SamplesSkipped)
+
+	// Retried requests overwrite the points.
+	wr = read[writeResponse](t, write(timeseries[:2]...))
+	assert.Equal(t, 0, wr.Data.MetricsCreated)
+	assert.Equal(t, 2, wr.Data.MetricsOverwritten)
+
+	resp, err = httpC.Post(baseURL+"/processes/model-metrics", "application/json", bytes.NewBufferString(`["`+processID+`"]`))
+	require.NoError(t, err)
+	gmr := read[getModelMetricsResponse](t, resp)
+	require.Len(t, gmr.Data.Sections["default"], 1)
+	panel := gmr.Data.Sections["default"][0]
+	assert.Equal(t, "loss", panel.Title)
+	assert.Equal(t, []interface{}{float64(1), float64(2)}, panel.Series[0].Values)
+	assert.Equal(t, []interface{}{float64(2), float64(1)}, panel.Series[1].Values)
+
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/write", bytes.NewBufferString("not snappy"))
+	require.NoError(t, err)
+	resp, err = httpC.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestMetricsFromWriteRequestWithStepSeries(t *testing.T) {
+	cfg := RemoteWriteConfig{
+		ProcessLabel: "run",
+		MetricLabel:  "__name__",
+		MetricPrefix: "train_",
+		StepSeries:   "step",
+		StepName:     "batch",
+	}
+	processID := uuid.New()
+	writeRequest := &prompb.WriteRequest{Timeseries: []*prompb.TimeSeries{
+		series([]*prompb.Sample{{Value: 0.5, Timestamp: 500}, {Value: 0.4, Timestamp: 1000}, {Value: 0.3, Timestamp: 1500}, {Value: 0.2, Timestamp: 2000}},
+			"__name__", "train_loss", "run", processID.String()),
+		series([]*prompb.Sample{{Value: 20, Timestamp: 2000}, {Value: 10, Timestamp: 1000}, {Value: 1.5, Timestamp: 3000}},
+			"__name__", "train_step", "run", processID.String()),
+		series([]*prompb.Sample{{Value: 1, Timestamp: 1000}},
+			"__name__", "process_cpu_seconds_total", "run", processID.String()),
+	}}
+
+	metrics, skipped := metricsFromWriteRequest(cfg, writeRequest, time.Now())
+	// The loss before the first step and the fractional step are skipped, as
+	// is the series without the prefix.
+	assert.Equal(t, 3, skipped)
+	got := map[uint32]float64{}
+	for _, m := range metrics[processID] {
+		assert.Equal(t, "loss", m.MetricName)
+		assert.Equal(t, "batch", m.StepName)
+		got[m.Step] = m.Float64()
+	}
+	assert.Equal(t, map[uint32]float64{10: 0.3, 20: 0.2}, got)
+	assert.Len(t, metrics, 1)
+}
+
+func TestMetricsFromWriteRequestWithCollidingSeries(t *testing.T) {
+	processID := uuid.New()
+	writeRequest := &prompb.WriteRequest{Timeseries: []*prompb.TimeSeries{
+		series([]*prompb.Sample{{Value: 0.5, Timestamp: 1000}, {Value: 0.4, Timestamp: 2000}},
+			"__name__", "loss", "process_id", processID.String(), "step", "1", "phase", "train"),
+		series([]*prompb.Sample{{Value: 0.7, Timestamp: 1000}},
+			"__name__", "loss", "process_id", processID.String(), "step", "1", "phase", "eval"),
+	}}
+
+	cfg := RemoteWriteConfig{ProcessLabel: "process_id", MetricLabel: "__name__", StepLabel: "step", StepName: "step"}
+	metrics, skipped := metricsFromWriteRequest(cfg, writeRequest, time.Now())
+	// Without a section label the phases map to the same point: the samples
+	// of the first series are kept, the latest of them winning when saved,
+	// and the second series is skipped.
+	assert.Equal(t, 1, skipped)
+	require.Len(t, metrics[processID], 2)
+	for _, m := range metrics[processID] {
+		assert.Equal(t, "loss", m.MetricName)
+	}
+	assert.Equal(t, 0.4, metrics[processID][1].Float64())
+
+	cfg.SectionLabel = "phase"
+	metrics, skipped = metricsFromWriteRequest(cfg, writeRequest, time.Now())
+	assert.Equal(t, 0, skipped)
+	got := map[string]float64{}
+	for _, m := range metrics[processID] {
+		got[m.MetricName] = m.Float64()
+	}
+	assert.Equal(t, map[string]float64{"train/loss": 0.4, "eval/loss": 0.7}, got)
+}
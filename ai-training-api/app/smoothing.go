@@ -0,0 +1,222 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+)
+
+// Methods of smoothing the series of the panels, selected with the smoothing
+// query parameter. Smoothed series are added next to the raw ones.
+const (
+	// An exponential moving average debiased like TensorBoard's, so the
+	// first points aren't pulled towards zero. Its weight is set with
+	// smoothing_weight, between 0 (no smoothing) and 1 excluded.
+	smoothingEMA = "ema"
+	// The average of the last smoothing_window points.
+	smoothingMovingAverage = "moving_average"
+	// The average of the points around each one, weighted by a Gaussian
+	// kernel with a standard deviation of smoothing_sigma points.
+	smoothingGaussian = "gaussian"
+)
+
+const (
+	defaultSmoothingWeight = 0.6
+	defaultSmoothingWindow = 10
+	defaultSmoothingSigma  = 2
+	// maxSmoothingSigma bounds the cost of Gaussian smoothing, whose kernel
+	// spans 6 standard deviations.
+	maxSmoothingSigma = 100
+)
+
+// Labels of smoothed fields. They are named after the field they smooth and
+// their method, e.g. "<process ID> (ema)", since clients tell fields apart by
+// name.
+const (
+	// smoothingLabel holds the method of smoothed fields.
+	smoothingLabel = "smoothing"
+	// smoothedLabel holds the name of the field they smooth.
+	smoothedLabel = "smoothed"
+)
+
+// smoothingSpec is how to smooth the series of the panels.
+type smoothingSpec struct {
+	method string
+	weight float64
+	window int
+	sigma  float64
+}
+
+// parseSmoothingSpec returns the smoothing of a request, or nil if its
+// series aren't smoothed.
+func parseSmoothingSpec(query url.Values) (*smoothingSpec, error) {
+	spec := &smoothingSpec{
+		method: query.Get("smoothing"),
+		weight: defaultSmoothingWeight,
+		window: defaultSmoothingWindow,
+		sigma:  defaultSmoothingSigma,
+	}
+	parameter := map[string]string{
+		smoothingEMA:           "smoothing_weight",
+		smoothingMovingAverage: "smoothing_window",
+		smoothingGaussian:      "smoothing_sigma",
+	}
+	if _, ok := parameter[spec.method]; !ok && spec.method != "" {
+		return nil, fmt.Errorf("smoothing must be %q, %q or %q", smoothingEMA, smoothingMovingAverage, smoothingGaussian)
+	}
+	for method, name := range parameter {
+		if query.Get(name) != "" && method != spec.method {
+			return nil, fmt.Errorf("%s requires smoothing=%s", name, method)
+		}
+	}
+
+	var err error
+	switch v := query.Get(parameter[spec.method]); {
+	case spec.method == "":
+		return nil, nil
+	case v == "":
+	case spec.method == smoothingEMA:
+		spec.weight, err = strconv.ParseFloat(v, 64)
+		if err != nil || spec.weight < 0 || spec.weight >= 1 {
+			return nil, fmt.Errorf("smoothing_weight must be a number from 0 to 1 excluded")
+		}
+	case spec.method == smoothingMovingAverage:
+		spec.window, err = strconv.Atoi(v)
+		if err != nil || spec.window < 1 {
+			return nil, fmt.Errorf("smoothing_window must be a positive integer")
+		}
+	case spec.method == smoothingGaussian:
+		spec.sigma, err = strconv.ParseFloat(v, 64)
+		if err != nil || !(spec.sigma > 0 && spec.sigma <= maxSmoothingSigma) {
+			return nil, fmt.Errorf("smoothing_sigma must be a positive number of at most %d", maxSmoothingSigma)
+		}
+	}
+	return spec, nil
+}
+
+// smoothResponse adds the smoothed series of each panel of a response next
+// to the raw ones.
+func smoothResponse(response GetModelMetricsResponse, spec smoothingSpec) {
+	for _, panels := range response.Sections {
+		for i := range panels {
+			panels[i].Series = smoothFrame(panels[i].Series, spec)
+		}
+	}
+}
+
+// smoothFrame returns the frame with each series followed by its smoothed
+// series. Series are smoothed over their own points, skipping the nulls of
+// the positions they have no point at. NaN and infinities are kept as is,
+// and left out of the smoothing of the other points.
+func smoothFrame(frame DataFrame, spec smoothingSpec) DataFrame {
+	if len(frame) == 0 {
+		return frame
+	}
+	out := make(DataFrame, 0, 2*len(frame)-1)
+	out = append(out, frame[0])
+	for _, field := range frame[1:] {
+		values := fieldNumbers(field)
+		smoothed := Field{
+			Name:   fmt.Sprintf("%s (%s)", field.Name, spec.method),
+			Type:   field.Type,
+			Labels: map[string]string{smoothingLabel: spec.method, smoothedLabel: field.Name},
+			Values: make([]interface{}, 0, len(values)),
+		}
+		for _, v := range spec.smooth(values) {
+			smoothed.appendNumber(v)
+		}
+		out = append(out, field, smoothed)
+	}
+	return out
+}
+
+// smooth returns the smoothed values of a series.
+func (s smoothingSpec) smooth(values []*float64) []*float64 {
+	smoothed := make([]*float64, len(values))
+	// The finite values, which are smoothed, and their indices.
+	var indices []int
+	var ys []float64
+	for i, v := range values {
+		switch {
+		case v == nil:
+		case math.IsNaN(*v) || math.IsInf(*v, 0):
+			smoothed[i] = v
+		default:
+			indices = append(indices, i)
+			ys = append(ys, *v)
+		}
+	}
+
+	var result []float64
+	switch s.method {
+	case smoothingEMA:
+		result = emaDebiased(ys, s.weight)
+	case smoothingMovingAverage:
+		result = movingAverage(ys, s.window)
+	case smoothingGaussian:
+		result = gaussianSmooth(ys, s.sigma)
+	}
+	for j, i := range indices {
+		smoothed[i] = &result[j]
+	}
+	return smoothed
+}
+
+// emaDebiased returns the exponential moving average of the values,
+// divided by the total of the weights of the points so far, like
+// TensorBoard's smoothing.
+func emaDebiased(ys []float64, weight float64) []float64 {
+	smoothed := make([]float64, len(ys))
+	last := 0.0
+	for i, y := range ys {
+		last = last*weight + (1-weight)*y
+		smoothed[i] = last / (1 - math.Pow(weight, float64(i+1)))
+	}
+	return smoothed
+}
+
+// movingAverage returns the average of each value with the values before
+// it, up to window values.
+func movingAverage(ys []float64, window int) []float64 {
+	smoothed := make([]float64, len(ys))
+	sum := 0.0
+	for i, y := range ys {
+		sum += y
+		if i >= window {
+			sum -= ys[i-window]
+		}
+		smoothed[i] = sum / float64(min(i+1, window))
+	}
+	return smoothed
+}
+
+// gaussianSmooth returns the average of the values around each value,
+// weighted by a Gaussian kernel of the distance in points. The kernel is cut
+// at 3 standard deviations, and renormalized near the ends of the series.
+func gaussianSmooth(ys []float64, sigma float64) []float64 {
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, radius+1)
+	for d := range kernel {
+		kernel[d] = math.Exp(-float64(d*d) / (2 * sigma * sigma))
+	}
+
+	smoothed := make([]float64, len(ys))
+	for i := range ys {
+		var sum, weights float64
+		for j := max(i-radius, 0); j <= min(i+radius, len(ys)-1); j++ {
+			w := kernel[abs(i-j)]
+			sum += w * ys[j]
+			weights += w
+		}
+		smoothed[i] = sum / weights
+	}
+	return smoothed
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"math"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmoothingMethods(t *testing.T) {
+	// The first point isn't pulled towards zero.
+	assert.InDeltaSlice(t, []float64{1, 5.0 / 3, 17.0 / 7}, emaDebiased([]float64{1, 2, 3}, 0.5), 1e-9)
+	assert.Equal(t, []float64{1, 2, 3}, emaDebiased([]float64{1, 2, 3}, 0))
+
+	assert.Equal(t, []float64{1, 1.5, 2.5, 3.5}, movingAverage([]float64{1, 2, 3, 4}, 2))
+
+	assert.InDeltaSlice(t, []float64{2, 2, 2, 2}, gaussianSmooth([]float64{2, 2, 2, 2}, 1), 1e-9)
+	spike := gaussianSmooth([]float64{0, 0, 0, 9, 0, 0, 0}, 1)
+	assert.InDelta(t, spike[2], spike[4], 1e-9)
+	assert.Less(t, spike[3], 9.0)
+	assert.Greater(t, spike[3], spike[2])
+}
+
+func TestSmoothFrame(t *testing.T) {
+	one, three := 1.0, 3.0
+	inf := math.Inf(1)
+	frame := DataFrame{
+		{Name: "step", Type: "number", Values: []interface{}{uint32(1), uint32(2), uint32(3), uint32(4)}},
+		{Name: "p1", Type: "number"},
+	}
+	for _, v := range []*float64{&one, nil, &inf, &three} {
+		frame[1].appendNumber(v)
+	}
+
+	smoothed := smoothFrame(frame, smoothingSpec{method: smoothingMovingAverage, window: 2})
+	require.Len(t, smoothed, 3)
+	assert.Equal(t, frame[1], smoothed[1])
+	// Nulls stay null and infinities are kept, but skipped by the average.
+	assert.Equal(t, Field{
+		Name:     "p1 (moving_average)",
+		Type:     "number",
+		Labels:   map[string]string{smoothingLabel: smoothingMovingAverage, smoothedLabel: "p1"},
+		Values:   []interface{}{1.0, nil, nil, 2.0},
+		Entities: &FieldEntities{Inf: []int{2}},
+	}, smoothed[2])
+}
+
+func TestParseSmoothingSpec(t *testing.T) {
+	spec, err := parseSmoothingSpec(url.Values{})
+	require.NoError(t, err)
+	assert.Nil(t, spec)
+
+	spec, err = parseSmoothingSpec(url.Values{"smoothing": {smoothingEMA}})
+	require.NoError(t, err)
+	assert.Equal(t, defaultSmoothingWeight, spec.weight)
+
+	spec, err = parseSmoothingSpec(url.Values{"smoothing": {smoothingGaussian}, "smoothing_sigma": {"4.5"}})
+	require.NoError(t, err)
+	assert.Equal(t, 4.5, spec.sigma)
+
+	for _, query := range []url.Values{
+		{"smoothing": {"median"}},
+		{"smoothing": {smoothingEMA}, "smoothing_weight": {"1"}},
+		{"smoothing": {smoothingEMA}, "smoothing_window": {"5"}},
+		{"smoothing": {smoothingMovingAverage}, "smoothing_window": {"0"}},
+		{"smoothing": {smoothingGaussian}, "smoothing_sigma": {"1000"}},
+		{"smoothing_weight": {"0.9"}},
+	} {
+		_, err := parseSmoothingSpec(query)
+		assert.Error(t, err, query.Encode())
+	}
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+)
+
+// MetricSummary summarizes the points of a metric of a process logged
+// against a step name, e.g. to rank processes.
+type MetricSummary struct {
+	ProcessID  uuid.UUID `json:"process_id"`
+	MetricName string    `json:"metric_name"`
+	StepName   string    `json:"step_name"`
+	// Count is the number of points, NonFiniteCount how many of them are
+	// NaN or infinite.
+	Count          int    `json:"count"`
+	NonFiniteCount int    `json:"non_finite_count"`
+	FirstStep      uint32 `json:"first_step"`
+	LastStep       uint32 `json:"last_step"`
+	// Last is the value at the last step, which may be NaN or infinite.
+	Last MetricValue `json:"last"`
+	// Min, Max and Mean are of the finite values, null if there are none.
+	// MinStep and MaxStep are the first steps Min and Max were logged at.
+	Min     *MetricValue `json:"min"`
+	MinStep *uint32      `json:"min_step"`
+	Max     *MetricValue `json:"max"`
+	MaxStep *uint32      `json:"max_step"`
+	Mean    *MetricValue `json:"mean"`
+}
+
+// metricSummaryRow is a row of the summaries query.
+type metricSummaryRow struct {
+	ProcessID      uuid.UUID
+	MetricName     string
+	StepName       string
+	Count          int
+	NonFiniteCount int
+	FirstStep      uint32
+	LastStep       uint32
+	MinValue       sql.NullFloat64
+	MaxValue       sql.NullFloat64
+	MeanValue      sql.NullFloat64
+	MinStep        sql.NullInt64
+	MaxStep        sql.NullInt64
+	LastValue      sql.NullFloat64
+	LastNonFinite  sql.NullString
+}
+
+// findMetricSummaries computes the summaries of the metrics selected by the
+// query, sorted by process, metric and step name. They are computed when
+// requested rather than maintained as points are logged, since overwritten
+// points could invalidate a running minimum or maximum: a first pass
+// aggregates each series, a second finds the value at its last step and
+// the steps of its extremes. SQL aggregates skip the NULLs NaN and
+// infinities are stored as, so only the finite values are aggregated.
+func findMetricSummaries(ctx context.Context, db *gorm.DB, tenantID string, query ModelMetricsQuery) ([]MetricSummary, error) {
+	where, args := query.where("")
+	whereM, argsM := query.where("m")
+
+	statement := `
+		WITH summaries AS (
+			SELECT
+				process_id,
+				metric_name,
+				step_name,
+				COUNT(*) AS count,
+				SUM(CASE WHEN non_finite <> '' THEN 1 ELSE 0 END) AS non_finite_count,
+				MIN(step) AS first_step,
+				MAX(step) AS last_step,
+				MIN(metric_value) AS min_value,
+				MAX(metric_value) AS max_value,
+				AVG(metric_value) AS mean_value
+			FROM model_metrics
+			WHERE tenant_id = ? AND ` + where + `
+			GROUP BY process_id, metric_name, step_name
+		)
+		SELECT
+			s.process_id,
+			s.metric_name,
+			s.step_name,
+			s.count,
+			s.non_finite_count,
+			s.first_step,
+			s.last_step,
+			s.min_value,
+			s.max_value,
+			s.mean_value,
+			MIN(CASE WHEN m.metric_value = s.min_value THEN m.step END) AS min_step,
+			MIN(CASE WHEN m.metric_value = s.max_value THEN m.step END) AS max_step,
+			MAX(CASE WHEN m.step = s.last_step THEN m.metric_value END) AS last_value,
+			MAX(CASE WHEN m.step = s.last_step THEN m.non_finite END) AS last_non_finite
+		FROM summaries s
+		JOIN model_metrics m ON m.tenant_id = ?
+			AND m.process_id = s.process_id
+			AND m.metric_name = s.metric_name
+			AND m.step_name = s.step_name
+		WHERE ` + whereM + `
+		GROUP BY s.process_id, s.metric_name, s.step_name, s.count, s.non_finite_count,
+			s.first_step, s.last_step, s.min_value, s.max_value, s.mean_value
+	`
+	vars := []interface{}{tenantID}
+	vars = append(vars, args...)
+	vars = append(vars, tenantID)
+	vars = append(vars, argsM...)
+
+	var rows []metricSummaryRow
+	if err := db.WithContext(ctx).Raw(statement, vars...).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("error executing query: %v", err)
+	}
+
+	summaries := make([]MetricSummary, 0, len(rows))
+	for _, row := range rows {
+		if !query.matchesMetric(row.MetricName) {
+			continue
+		}
+		summaries = append(summaries, row.summary())
+	}
+	slices.SortFunc(summaries, func(a, b MetricSummary) int {
+		if c := bytes.Compare(a.ProcessID[:], b.ProcessID[:]); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.MetricName, b.MetricName); c != 0 {
+			return c
+		}
+		return strings.Compare(a.StepName, b.StepName)
+	})
+	return summaries, nil
+}
+
+func (r metricSummaryRow) summary() MetricSummary {
+	return MetricSummary{
+		ProcessID:      r.ProcessID,
+		MetricName:     r.MetricName,
+		StepName:       r.StepName,
+		Count:          r.Count,
+		NonFiniteCount: r.NonFiniteCount,
+		FirstStep:      r.FirstStep,
+		LastStep:       r.LastStep,
+		Last:           MetricValue(model.JoinMetricValue(r.LastValue, r.LastNonFinite.String)),
+		Min:            nullMetricValue(r.MinValue),
+		MinStep:        nullStep(r.MinStep),
+		Max:            nullMetricValue(r.MaxValue),
+		MaxStep:        nullStep(r.MaxStep),
+		Mean:           nullMetricValue(r.MeanValue),
+	}
+}
+
+func nullMetricValue(v sql.NullFloat64) *MetricValue {
+	if !v.Valid {
+		return nil
+	}
+	value := MetricValue(v.Float64)
+	return &value
+}
+
+func nullStep(v sql.NullInt64) *uint32 {
+	if !v.Valid {
+		return nil
+	}
+	step := uint32(v.Int64)
+	return &step
+}
+
+// getMetricSummaries returns the summaries of the metrics selected by the
+// ModelMetricsQuery in the body.
+func (a *App) getMetricSummaries(tenantID string, req *http.Request) (interface{}, error) {
+	query, err := parseModelMetricsQuery(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := findMetricSummaries(req.Context(), a.db(req.Context()), tenantID, query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metric summaries: %w", err)
+	}
+
+	level.Info(a.logger).Log("msg", "found metric summaries", "tenantID", tenantID, "processes", len(query.ProcessIDs), "summaries", len(summaries))
+	return summaries, nil
+}
+
+// wantsMetricSummaries reports whether the metric_summaries query parameter
+// asks for the summaries of the metrics of processes to be embedded.
+func wantsMetricSummaries(query url.Values) (bool, error) {
+	v := query.Get("metric_summaries")
+	if v == "" {
+		return false, nil
+	}
+	want, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("metric_summaries must be true or false")
+	}
+	return want, nil
+}
+
+// metricSummariesByProcess returns the summaries of all metrics of the
+// processes, by process.
+func metricSummariesByProcess(ctx context.Context, db *gorm.DB, tenantID string, processIDs []uuid.UUID) (map[uuid.UUID][]MetricSummary, error) {
+	summaries, err := findMetricSummaries(ctx, db, tenantID, ModelMetricsQuery{ProcessIDs: processIDs})
+	if err != nil {
+		return nil, fmt.Errorf("error getting metric summaries: %w", err)
+	}
+	byProcess := make(map[uuid.UUID][]MetricSummary, len(processIDs))
+	for _, s := range summaries {
+		byProcess[s.ProcessID] = append(byProcess[s.ProcessID], s)
+	}
+	return byProcess, nil
+}
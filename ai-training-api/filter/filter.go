@@ -0,0 +1,170 @@
+// Package filter implements a small expression language used to find
+// processes by their metadata, for example:
+//
+//	optimizer = "adam" AND lr < 0.001 AND model.layers >= 12
+//
+// Expressions are made of comparisons between a flattened metadata key and a
+// literal, combined with AND, OR, NOT and parentheses. Keys are dot separated
+// identifiers, or arbitrary strings quoted with backticks. Literals are
+// double-quoted strings, numbers and the booleans true and false.
+//
+// A comparison only matches metadata of the same type as its literal: a
+// number literal matches numeric values, a string literal matches string
+// values. Like in SQL, `key != value` only matches processes that have
+// the key set to a different value; use NOT (key = value) to also match
+// processes without the key.
+package filter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/model"
+)
+
+// Operator is a comparison operator.
+type Operator string
+
+const (
+	OpEqual        Operator = "="
+	OpNotEqual     Operator = "!="
+	OpLess         Operator = "<"
+	OpLessEqual    Operator = "<="
+	OpGreater      Operator = ">"
+	OpGreaterEqual Operator = ">="
+)
+
+// valid reports whether op is one of the comparison operators.
+func (op Operator) valid() bool {
+	switch op {
+	case OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual:
+		return true
+	}
+	return false
+}
+
+// Expr is a node of a parsed filter expression.
+type Expr interface {
+	fmt.Stringer
+	expr()
+}
+
+// And matches when both sides match.
+type And struct {
+	Left, Right Expr
+}
+
+// Or matches when either side matches.
+type Or struct {
+	Left, Right Expr
+}
+
+// Not matches when the inner expression does not match.
+type Not struct {
+	Expr Expr
+}
+
+// Comparison compares the metadata value stored under Key with Value. Value
+// is a string, a float64 or a bool.
+type Comparison struct {
+	Key   string
+	Op    Operator
+	Value interface{}
+}
+
+func (And) expr()        {}
+func (Or) expr()         {}
+func (Not) expr()        {}
+func (Comparison) expr() {}
+
+func (e And) String() string { return "(" + e.Left.String() + " AND " + e.Right.String() + ")" }
+func (e Or) String() string  { return "(" + e.Left.String() + " OR " + e.Right.String() + ")" }
+func (e Not) String() string { return "NOT " + e.Expr.String() }
+
+func (e Comparison) String() string {
+	var value string
+	switch v := e.Value.(type) {
+	case string:
+		value = fmt.Sprintf("%q", v)
+	default:
+		value = fmt.Sprintf("%v", v)
+	}
+	return fmt.Sprintf("`%s` %s %s", e.Key, e.Op, value)
+}
+
+// Compile turns a filter expression into a SQL condition on the processes
+// table. Each comparison becomes a sub-query over metadata_kvs restricted to
+// the given tenant.
+func Compile(e Expr, tenantID string) (string, []interface{}, error) {
+	var args []interface{}
+	sql, err := compile(e, tenantID, &args)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+func compile(e Expr, tenantID string, args *[]interface{}) (string, error) {
+	switch e := e.(type) {
+	case And:
+		return compileBinary("AND", e.Left, e.Right, tenantID, args)
+	case Or:
+		return compileBinary("OR", e.Left, e.Right, tenantID, args)
+	case Not:
+		inner, err := compile(e.Expr, tenantID, args)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + inner + ")", nil
+	case Comparison:
+		return compileComparison(e, tenantID, args)
+	}
+	return "", fmt.Errorf("unsupported expression %T", e)
+}
+
+func compileBinary(op string, left, right Expr, tenantID string, args *[]interface{}) (string, error) {
+	l, err := compile(left, tenantID, args)
+	if err != nil {
+		return "", err
+	}
+	r, err := compile(right, tenantID, args)
+	if err != nil {
+		return "", err
+	}
+	return "(" + l + " " + op + " " + r + ")", nil
+}
+
+func compileComparison(c Comparison, tenantID string, args *[]interface{}) (string, error) {
+	// Comparisons built in code have not been checked by the parser, and
+	// the operator is written into the SQL as is.
+	if !c.Op.valid() {
+		return "", fmt.Errorf("unknown operator %q", c.Op)
+	}
+	var cond string
+	switch v := c.Value.(type) {
+	case string:
+		cond = "type = ? AND value " + string(c.Op) + " ?"
+		*args = append(*args, tenantID, c.Key, model.MetadataTypeString, []byte(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("cannot compare %s with %v", c.Key, v)
+		}
+		cond = "type IN ? AND number " + string(c.Op) + " ?"
+		*args = append(*args, tenantID, c.Key, model.NumericMetadataTypes, v)
+	case bool:
+		if c.Op != OpEqual && c.Op != OpNotEqual {
+			return "", fmt.Errorf("operator %s is not supported for booleans", c.Op)
+		}
+		cond = "type = ? AND value " + string(c.Op) + " ?"
+		*args = append(*args, tenantID, c.Key, model.MetadataTypeBool, []byte(fmt.Sprintf("%t", v)))
+	default:
+		return "", fmt.Errorf("unsupported value %v for %s", c.Value, c.Key)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("id IN (SELECT process_id FROM metadata_kvs WHERE tenant_id = ? AND `key` = ? AND ")
+	sb.WriteString(cond)
+	sb.WriteString(")")
+	return sb.String(), nil
+}
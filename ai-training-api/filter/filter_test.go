@@ -0,0 +1,133 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "string comparison",
+			input:    `optimizer = "adam"`,
+			expected: "`optimizer` = \"adam\"",
+		},
+		{
+			name:     "number comparison with dotted key",
+			input:    `model.layers >= 12`,
+			expected: "`model.layers` >= 12",
+		},
+		{
+			name:     "scientific notation",
+			input:    `lr < 1e-3`,
+			expected: "`lr` < 0.001",
+		},
+		{
+			name:     "negative number",
+			input:    `bias != -0.5`,
+			expected: "`bias` != -0.5",
+		},
+		{
+			name:     "boolean",
+			input:    `use_amp = TRUE`,
+			expected: "`use_amp` = true",
+		},
+		{
+			name:     "quoted key",
+			input:    "`my key` = \"x\"",
+			expected: "`my key` = \"x\"",
+		},
+		{
+			name:     "AND binds tighter than OR",
+			input:    `a = 1 OR b = 2 and c = 3`,
+			expected: "(`a` = 1 OR (`b` = 2 AND `c` = 3))",
+		},
+		{
+			name:     "parentheses and NOT",
+			input:    `NOT (a = 1 OR b = 2) AND c = "x"`,
+			expected: "(NOT (`a` = 1 OR `b` = 2) AND `c` = \"x\")",
+		},
+		{
+			name:     "example from the docs",
+			input:    `optimizer = "adam" AND lr < 0.001 AND model.layers >= 12`,
+			expected: "((`optimizer` = \"adam\" AND `lr` < 0.001) AND `model.layers` >= 12)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Parse(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, e.String())
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		errorContains string
+	}{
+		{name: "empty", input: "", errorContains: "unexpected end of filter"},
+		{name: "missing operator", input: "a 1", errorContains: "expected an operator"},
+		{name: "missing value", input: "a =", errorContains: "expected a value"},
+		{name: "bare identifier value", input: "a = adam", errorContains: `expected a value, got "adam"`},
+		{name: "unterminated string", input: `a = "adam`, errorContains: "unterminated string"},
+		{name: "unbalanced parentheses", input: "(a = 1", errorContains: `expected ")"`},
+		{name: "trailing tokens", input: "a = 1 b = 2", errorContains: `unexpected "b"`},
+		{name: "bang without equals", input: "a ! 1", errorContains: `expected "!="`},
+		{name: "bare bang", input: "!", errorContains: `expected "!="`},
+		{name: "double equals", input: "a == 1", errorContains: `unknown operator "=="`},
+		{name: "invalid number", input: "a = 1.2.3", errorContains: "invalid number"},
+		{name: "unknown character", input: "a = 1 & b = 2", errorContains: "unexpected character"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorContains)
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	e, err := Parse(`optimizer = "adam" AND (lr < 0.001 OR NOT use_amp = true)`)
+	require.NoError(t, err)
+
+	sql, args, err := Compile(e, "tenant")
+	require.NoError(t, err)
+
+	sub := "id IN (SELECT process_id FROM metadata_kvs WHERE tenant_id = ? AND `key` = ? AND "
+	assert.Equal(t,
+		"("+sub+"type = ? AND value = ?) AND ("+sub+"type IN ? AND number < ?) OR NOT ("+sub+"type = ? AND value = ?))))",
+		sql,
+	)
+	assert.Equal(t, []interface{}{
+		"tenant", "optimizer", "string", []byte("adam"),
+		"tenant", "lr", []string{"int", "float", "number"}, 0.001,
+		"tenant", "use_amp", "bool", []byte("true"),
+	}, args)
+}
+
+func TestCompileRejectsOrderingOnBooleans(t *testing.T) {
+	e, err := Parse(`use_amp < true`)
+	require.NoError(t, err)
+
+	_, _, err = Compile(e, "tenant")
+	assert.ErrorContains(t, err, "not supported for booleans")
+}
+
+func TestCompileRejectsUnknownOperators(t *testing.T) {
+	e := Comparison{Key: "lr", Op: "< 0 OR 1 =", Value: 0.1}
+
+	_, _, err := Compile(e, "tenant")
+	assert.ErrorContains(t, err, "unknown operator")
+}
@@ -0,0 +1,270 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxDepth limits how deeply expressions can be nested.
+const maxDepth = 32
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// ParseError describes where and why parsing a filter failed.
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid filter at position %d: %s", e.Pos, e.Msg)
+}
+
+// Parse parses a filter expression.
+func Parse(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return e, nil
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			op := input[i : i+1]
+			if i+1 < len(input) && input[i+1] == '=' {
+				op = input[i : i+2]
+			}
+			if op == "!" {
+				return nil, &ParseError{Pos: i, Msg: `expected "!="`}
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		case r == '"':
+			end := i + 1
+			for end < len(input) && input[end] != '"' {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(input) {
+				return nil, &ParseError{Pos: i, Msg: "unterminated string"}
+			}
+			s, err := strconv.Unquote(input[i : end+1])
+			if err != nil {
+				return nil, &ParseError{Pos: i, Msg: "invalid string"}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s, pos: i})
+			i = end + 1
+		case r == '`':
+			end := strings.IndexByte(input[i+1:], '`')
+			if end < 0 {
+				return nil, &ParseError{Pos: i, Msg: "unterminated quoted key"}
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: input[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case r == '-' || r == '+' || r == '.' || unicode.IsDigit(r):
+			end := i + 1
+			for end < len(input) && isNumberChar(input[end], input[end-1]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: input[i:end], pos: i})
+			i = end
+		case r == '_' || unicode.IsLetter(r):
+			end := i + size
+			for end < len(input) {
+				r, size := utf8.DecodeRuneInString(input[end:])
+				if !isIdentChar(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: input[i:end], pos: i})
+			i = end
+		default:
+			return nil, &ParseError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(input)})
+	return tokens, nil
+}
+
+func isIdentChar(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || r == '/' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isNumberChar(c, prev byte) bool {
+	switch {
+	case c >= '0' && c <= '9', c == '.', c == 'e', c == 'E':
+		return true
+	case c == '-' || c == '+':
+		return prev == 'e' || prev == 'E'
+	}
+	return false
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokenIdent && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr(depth int) (Expr, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (Expr, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot(depth int) (Expr, error) {
+	if depth > maxDepth {
+		return nil, &ParseError{Pos: p.peek().pos, Msg: "expression is nested too deeply"}
+	}
+	if p.keyword("NOT") {
+		e, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: e}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *parser) parsePrimary(depth int) (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLParen:
+		e, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &ParseError{Pos: closing.pos, Msg: `expected ")"`}
+		}
+		return e, nil
+	case tokenIdent:
+		return p.parseComparison(t)
+	case tokenEOF:
+		return nil, &ParseError{Pos: t.pos, Msg: "unexpected end of filter"}
+	}
+	return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("expected a key, got %q", t.text)}
+}
+
+func (p *parser) parseComparison(key token) (Expr, error) {
+	op := p.next()
+	if op.kind != tokenOperator {
+		return nil, &ParseError{Pos: op.pos, Msg: fmt.Sprintf("expected an operator after %q", key.text)}
+	}
+	if !Operator(op.text).valid() {
+		return nil, &ParseError{Pos: op.pos, Msg: fmt.Sprintf("unknown operator %q", op.text)}
+	}
+
+	value := p.next()
+	c := Comparison{Key: key.text, Op: Operator(op.text)}
+	switch value.kind {
+	case tokenString:
+		c.Value = value.text
+	case tokenNumber:
+		f, err := strconv.ParseFloat(value.text, 64)
+		if err != nil {
+			return nil, &ParseError{Pos: value.pos, Msg: fmt.Sprintf("invalid number %q", value.text)}
+		}
+		c.Value = f
+	case tokenIdent:
+		switch strings.ToLower(value.text) {
+		case "true":
+			c.Value = true
+		case "false":
+			c.Value = false
+		default:
+			return nil, &ParseError{Pos: value.pos, Msg: fmt.Sprintf("expected a value, got %q", value.text)}
+		}
+	default:
+		return nil, &ParseError{Pos: value.pos, Msg: fmt.Sprintf("expected a value, got %q", value.text)}
+	}
+	return c, nil
+}
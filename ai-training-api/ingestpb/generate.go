@@ -0,0 +1,5 @@
+// Package ingestpb contains the protobuf messages and gRPC service used to
+// ingest processes and their metrics.
+package ingestpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ingest.proto
@@ -0,0 +1,269 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: ingest.proto
+
+package ingestpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Ingest_RegisterProcess_FullMethodName       = "/aitraining.ingest.v1.Ingest/RegisterProcess"
+	Ingest_UpdateProcessState_FullMethodName    = "/aitraining.ingest.v1.Ingest/UpdateProcessState"
+	Ingest_UpdateProcessMetadata_FullMethodName = "/aitraining.ingest.v1.Ingest/UpdateProcessMetadata"
+	Ingest_SendMetricPoints_FullMethodName      = "/aitraining.ingest.v1.Ingest/SendMetricPoints"
+)
+
+// IngestClient is the client API for Ingest service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type IngestClient interface {
+	// RegisterProcess registers a new running process, like POST /process/new.
+	RegisterProcess(ctx context.Context, in *RegisterProcessRequest, opts ...grpc.CallOption) (*Process, error)
+	// UpdateProcessState moves a process to a new state, like
+	// POST /process/{id}/state.
+	UpdateProcessState(ctx context.Context, in *UpdateProcessStateRequest, opts ...grpc.CallOption) (*Process, error)
+	// UpdateProcessMetadata merges metadata into the metadata of a process, like
+	// POST /process/{id}/update-metadata.
+	UpdateProcessMetadata(ctx context.Context, in *UpdateProcessMetadataRequest, opts ...grpc.CallOption) (*Process, error)
+	// SendMetricPoints saves metric points of a process as they are streamed,
+	// like POST /process/{id}/model-metrics.
+	SendMetricPoints(ctx context.Context, opts ...grpc.CallOption) (Ingest_SendMetricPointsClient, error)
+}
+
+type ingestClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIngestClient(cc grpc.ClientConnInterface) IngestClient {
+	return &ingestClient{cc}
+}
+
+func (c *ingestClient) RegisterProcess(ctx context.Context, in *RegisterProcessRequest, opts ...grpc.CallOption) (*Process, error) {
+	out := new(Process)
+	err := c.cc.Invoke(ctx, Ingest_RegisterProcess_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestClient) UpdateProcessState(ctx context.Context, in *UpdateProcessStateRequest, opts ...grpc.CallOption) (*Process, error) {
+	out := new(Process)
+	err := c.cc.Invoke(ctx, Ingest_UpdateProcessState_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestClient) UpdateProcessMetadata(ctx context.Context, in *UpdateProcessMetadataRequest, opts ...grpc.CallOption) (*Process, error) {
+	out := new(Process)
+	err := c.cc.Invoke(ctx, Ingest_UpdateProcessMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestClient) SendMetricPoints(ctx context.Context, opts ...grpc.CallOption) (Ingest_SendMetricPointsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Ingest_ServiceDesc.Streams[0], Ingest_SendMetricPoints_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ingestSendMetricPointsClient{stream}
+	return x, nil
+}
+
+type Ingest_SendMetricPointsClient interface {
+	Send(*SendMetricPointsRequest) error
+	CloseAndRecv() (*SendMetricPointsResponse, error)
+	grpc.ClientStream
+}
+
+type ingestSendMetricPointsClient struct {
+	grpc.ClientStream
+}
+
+func (x *ingestSendMetricPointsClient) Send(m *SendMetricPointsRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *ingestSendMetricPointsClient) CloseAndRecv() (*SendMetricPointsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(SendMetricPointsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// IngestServer is the server API for Ingest service.
+// All implementations must embed UnimplementedIngestServer
+// for forward compatibility
+type IngestServer interface {
+	// RegisterProcess registers a new running process, like POST /process/new.
+	RegisterProcess(context.Context, *RegisterProcessRequest) (*Process, error)
+	// UpdateProcessState moves a process to a new state, like
+	// POST /process/{id}/state.
+	UpdateProcessState(context.Context, *UpdateProcessStateRequest) (*Process, error)
+	// UpdateProcessMetadata merges metadata into the metadata of a process, like
+	// POST /process/{id}/update-metadata.
+	UpdateProcessMetadata(context.Context, *UpdateProcessMetadataRequest) (*Process, error)
+	// SendMetricPoints saves metric points of a process as they are streamed,
+	// like POST /process/{id}/model-metrics.
+	SendMetricPoints(Ingest_SendMetricPointsServer) error
+	mustEmbedUnimplementedIngestServer()
+}
+
+// UnimplementedIngestServer must be embedded to have forward compatible implementations.
+type UnimplementedIngestServer struct {
+}
+
+func (UnimplementedIngestServer) RegisterProcess(context.Context, *RegisterProcessRequest) (*Process, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterProcess not implemented")
+}
+func (UnimplementedIngestServer) UpdateProcessState(context.Context, *UpdateProcessStateRequest) (*Process, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateProcessState not implemented")
+}
+func (UnimplementedIngestServer) UpdateProcessMetadata(context.Context, *UpdateProcessMetadataRequest) (*Process, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateProcessMetadata not implemented")
+}
+func (UnimplementedIngestServer) SendMetricPoints(Ingest_SendMetricPointsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SendMetricPoints not implemented")
+}
+func (UnimplementedIngestServer) mustEmbedUnimplementedIngestServer() {}
+
+// UnsafeIngestServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IngestServer will
+// result in compilation errors.
+type UnsafeIngestServer interface {
+	mustEmbedUnimplementedIngestServer()
+}
+
+func RegisterIngestServer(s grpc.ServiceRegistrar, srv IngestServer) {
+	s.RegisterService(&Ingest_ServiceDesc, srv)
+}
+
+func _Ingest_RegisterProcess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestServer).RegisterProcess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ingest_RegisterProcess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestServer).RegisterProcess(ctx, req.(*RegisterProcessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ingest_UpdateProcessState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateProcessStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestServer).UpdateProcessState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ingest_UpdateProcessState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestServer).UpdateProcessState(ctx, req.(*UpdateProcessStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ingest_UpdateProcessMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateProcessMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestServer).UpdateProcessMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ingest_UpdateProcessMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestServer).UpdateProcessMetadata(ctx, req.(*UpdateProcessMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ingest_SendMetricPoints_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IngestServer).SendMetricPoints(&ingestSendMetricPointsServer{stream})
+}
+
+type Ingest_SendMetricPointsServer interface {
+	SendAndClose(*SendMetricPointsResponse) error
+	Recv() (*SendMetricPointsRequest, error)
+	grpc.ServerStream
+}
+
+type ingestSendMetricPointsServer struct {
+	grpc.ServerStream
+}
+
+func (x *ingestSendMetricPointsServer) SendAndClose(m *SendMetricPointsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *ingestSendMetricPointsServer) Recv() (*SendMetricPointsRequest, error) {
+	m := new(SendMetricPointsRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Ingest_ServiceDesc is the grpc.ServiceDesc for Ingest service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Ingest_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "aitraining.ingest.v1.Ingest",
+	HandlerType: (*IngestServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterProcess",
+			Handler:    _Ingest_RegisterProcess_Handler,
+		},
+		{
+			MethodName: "UpdateProcessState",
+			Handler:    _Ingest_UpdateProcessState_Handler,
+		},
+		{
+			MethodName: "UpdateProcessMetadata",
+			Handler:    _Ingest_UpdateProcessMetadata_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SendMetricPoints",
+			Handler:       _Ingest_SendMetricPoints_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "ingest.proto",
+}
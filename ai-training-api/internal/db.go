@@ -5,6 +5,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/avast/retry-go/v4"
@@ -121,15 +123,46 @@ func (logger *gormLogger) Error(_ context.Context, msg string, args ...interface
 
 func (logger *gormLogger) Trace(_ context.Context, begin time.Time, fc func() (string, int64), err error) {
 	elapsed := time.Since(begin)
-	sql, rows := fc()
 	if err != nil {
+		sql, rows := fc()
 		level.Error(logger).Log("msg", "error running database transaction", "err", err, "elapsed", elapsed, "sql", sql, "rows", rows)
 		return
 	}
 
 	if elapsed > time.Second {
+		sql, rows := fc()
 		level.Warn(logger).Log("msg", "slow database query", "elapsed", elapsed, "sql", sql, "rows", rows)
 		return
 	}
-	level.Debug(logger).Log("msg", "database query", "elapsed", elapsed, "sql", sql, "rows", rows)
+	// Rendering the SQL of large inserts costs more than running them, so it
+	// is only done if the debug line is actually written.
+	q := &tracedQuery{fc: fc}
+	level.Debug(logger).Log("msg", "database query", "elapsed", elapsed, "sql", querySQL{q}, "rows", queryRows{q})
+}
+
+// tracedQuery renders the SQL of a query the first time it is needed.
+type tracedQuery struct {
+	fc   func() (string, int64)
+	once sync.Once
+	sql  string
+	rows int64
+}
+
+func (q *tracedQuery) get() (string, int64) {
+	q.once.Do(func() { q.sql, q.rows = q.fc() })
+	return q.sql, q.rows
+}
+
+type querySQL struct{ q *tracedQuery }
+
+func (s querySQL) String() string {
+	sql, _ := s.q.get()
+	return sql
+}
+
+type queryRows struct{ q *tracedQuery }
+
+func (r queryRows) String() string {
+	_, rows := r.q.get()
+	return strconv.FormatInt(rows, 10)
 }
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/prometheus/client_golang/prometheus"
@@ -47,6 +49,10 @@ func run() int {
 			"web.listen-port",
 			"Port on which to expose metrics and web interface.",
 		).Default("8000").Int()
+		grpcListenPort = kingpin.Flag(
+			"grpc.listen-port",
+			"Port on which to expose the gRPC ingestion service.",
+		).Default("9095").Int()
 		databaseAddress = kingpin.Flag(
 			"database-address",
 			"Database connection string.",
@@ -67,6 +73,58 @@ func run() int {
 			"loki-tenant-id",
 			"Loki tenant ID to send logs to.",
 		).Default("").String()
+		heartbeatTimeout = kingpin.Flag(
+			"process.heartbeat-timeout",
+			"Time without a heartbeat after which a running process is marked as crashed. 0 disables it.",
+		).Default("1h").Duration()
+		tenantHeartbeatTimeouts = kingpin.Flag(
+			"process.tenant-heartbeat-timeout",
+			"Per-tenant override of the heartbeat timeout, as tenant=duration. Can be repeated.",
+		).StringMap()
+		maxMetricsPayloadSize = kingpin.Flag(
+			"metrics.max-payload-size",
+			"Largest model metrics request body accepted.",
+		).Default("16MB").Bytes()
+		remoteWriteProcessLabel = kingpin.Flag(
+			"remote-write.process-label",
+			"Label of Prometheus remote-write series holding the process ID. Series without it are skipped.",
+		).Default("process_id").String()
+		remoteWriteMetricLabel = kingpin.Flag(
+			"remote-write.metric-label",
+			"Label of Prometheus remote-write series holding the metric name.",
+		).Default("__name__").String()
+		remoteWriteMetricPrefix = kingpin.Flag(
+			"remote-write.metric-prefix",
+			"Prefix trimmed from the metric names of Prometheus remote-write series. Series without it are skipped.",
+		).String()
+		remoteWriteSectionLabel = kingpin.Flag(
+			"remote-write.section-label",
+			"Label of Prometheus remote-write series holding the section of the metric, logged as section/name. Series of a request that differ only by other labels are skipped but for the first.",
+		).String()
+		remoteWriteStepLabel = kingpin.Flag(
+			"remote-write.step-label",
+			"Label of Prometheus remote-write series holding the step.",
+		).Default("step").String()
+		remoteWriteStepSeries = kingpin.Flag(
+			"remote-write.step-series",
+			"Metric name of a Prometheus remote-write series whose value is the step of the other series of the process. Overrides remote-write.step-label.",
+		).String()
+		remoteWriteStepName = kingpin.Flag(
+			"remote-write.step-name",
+			"Step name Prometheus remote-write samples are logged under.",
+		).Default("step").String()
+		otlpProcessAttribute = kingpin.Flag(
+			"otlp.process-attribute",
+			"Resource attribute of OTLP metrics holding the process ID. Metrics of other resources are skipped.",
+		).Default("process_id").String()
+		otlpStepAttribute = kingpin.Flag(
+			"otlp.step-attribute",
+			"Data point attribute of OTLP metrics holding the step, also used as the step name.",
+		).Default("step").String()
+		otlpSectionAttribute = kingpin.Flag(
+			"otlp.section-attribute",
+			"Data point attribute of OTLP metrics holding the section of the metric, logged as section/name. Data points of a request that differ only by other attributes are rejected but for the first.",
+		).String()
 	)
 
 	// Allow configuration to be specified via environment variables.
@@ -78,14 +136,38 @@ func run() int {
 	kingpin.HelpFlag.Short('h')
 	kingpin.Parse()
 
+	tenantTimeouts, err := parseTenantDurations(*tenantHeartbeatTimeouts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
 	a, err := app.New(
 		*listenAddress,
 		*listenPort,
+		*grpcListenPort,
 		*databaseAddress,
 		*databaseType,
 		*constTenant,
 		*lokiAddress,
 		*lokiTenantID,
+		*heartbeatTimeout,
+		tenantTimeouts,
+		int64(*maxMetricsPayloadSize),
+		app.RemoteWriteConfig{
+			ProcessLabel: *remoteWriteProcessLabel,
+			MetricLabel:  *remoteWriteMetricLabel,
+			MetricPrefix: *remoteWriteMetricPrefix,
+			SectionLabel: *remoteWriteSectionLabel,
+			StepLabel:    *remoteWriteStepLabel,
+			StepSeries:   *remoteWriteStepSeries,
+			StepName:     *remoteWriteStepName,
+		},
+		app.OTLPConfig{
+			ProcessAttribute: *otlpProcessAttribute,
+			StepAttribute:    *otlpStepAttribute,
+			SectionAttribute: *otlpSectionAttribute,
+		},
 		promlogConfig)
 	if err != nil {
 		return 1
@@ -98,3 +180,16 @@ func run() int {
 
 	return 0
 }
+
+// parseTenantDurations parses per-tenant duration overrides.
+func parseTenantDurations(in map[string]string) (map[string]time.Duration, error) {
+	out := make(map[string]time.Duration, len(in))
+	for tenant, value := range in {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration for tenant %q: %w", tenant, err)
+		}
+		out[tenant] = d
+	}
+	return out, nil
+}
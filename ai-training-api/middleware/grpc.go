@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/dskit/middleware"
+	"github.com/grafana/dskit/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCAuthnMiddleware returns the gRPC interceptors that do what
+// AuthnMiddleware does for HTTP requests.
+func GRPCAuthnMiddleware(constTenant string) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	// This will be true in prod.
+	if constTenant == "" {
+		return middleware.ServerUserHeaderInterceptor, middleware.StreamServerUserHeaderInterceptor
+	}
+
+	// When we are using a constant tenant we need to inject the constant
+	// tenant into the context for the request.
+	// NOTE: THIS ONLY HAPPENS IN DEV!
+	unary := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(user.InjectOrgID(ctx, constTenant), req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, serverStream{
+			ServerStream: ss,
+			ctx:          user.InjectOrgID(ss.Context(), constTenant),
+		})
+	}
+	return unary, stream
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss serverStream) Context() context.Context {
+	return ss.ctx
+}
+
+// GRPCError returns the gRPC status of an error returned by API functions,
+// matching the HTTP status RequestResponseMiddleware would respond with.
+func GRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	switch err.(type) {
+	case errNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case errBadRequest:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errConflict:
+		return status.Error(codes.Aborted, err.Error())
+	case errPayloadTooLarge:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCAuthnMiddleware(t *testing.T) {
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return user.ExtractOrgID(ctx)
+	}
+
+	unary, _ := GRPCAuthnMiddleware("")
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("X-Scope-OrgID", "mytenant"))
+	tenant, err := unary(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "mytenant", tenant)
+
+	_, err = unary(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Error(t, err)
+
+	unary, _ = GRPCAuthnMiddleware("const")
+	tenant, err = unary(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "const", tenant)
+}
+
+func TestGRPCError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{ErrNotFound(errors.New("not found")), codes.NotFound},
+		{ErrBadRequest(errors.New("bad request")), codes.InvalidArgument},
+		{ErrConflict(errors.New("conflict")), codes.Aborted},
+		{ErrPayloadTooLarge(errors.New("payload too large")), codes.ResourceExhausted},
+		{context.Canceled, codes.Canceled},
+		{status.Error(codes.Unauthenticated, "no tenant"), codes.Unauthenticated},
+		{errors.New("internal server error"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			err := GRPCError(tt.err)
+			assert.Equal(t, tt.code, status.Code(err))
+			assert.Contains(t, err.Error(), tt.err.Error())
+		})
+	}
+	assert.NoError(t, GRPCError(nil))
+}
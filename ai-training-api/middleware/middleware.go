@@ -63,10 +63,13 @@ func RequestResponseMiddleware(logger log.Logger) func(Request) http.HandlerFunc
 
 			data, err := f(tenantID, req)
 			if err != nil {
-				statusCode := errorStatusCode(err)
+				statusCode := ErrorStatusCode(err)
 				level.Error(logger).Log("msg", "Error in api request", "err", err, "code", statusCode)
+				// Data returned with an error, if any, describes what was
+				// done before the request failed.
 				resp, _ := json.Marshal(ResponseWrapper{
 					Status: "error",
+					Data:   data,
 					Error:  err.Error(),
 				})
 				http.Error(w, string(resp), statusCode)
@@ -79,10 +82,16 @@ func RequestResponseMiddleware(logger log.Logger) func(Request) http.HandlerFunc
 				return
 			}
 
-			res, err := json.Marshal(ResponseWrapper{
+			wrapper := ResponseWrapper{
 				Status: "success",
 				Data:   data,
-			})
+			}
+			if page, ok := data.(Page); ok {
+				wrapper.Data = page.Items
+				wrapper.NextCursor = page.NextCursor
+			}
+
+			res, err := json.Marshal(wrapper)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -94,9 +103,17 @@ func RequestResponseMiddleware(logger log.Logger) func(Request) http.HandlerFunc
 }
 
 type ResponseWrapper struct {
-	Status string      `json:"status"`
-	Data   interface{} `json:"data,omitempty"`
-	Error  string      `json:"error,omitempty"`
+	Status     string      `json:"status"`
+	Data       interface{} `json:"data,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Page is returned by requests with paginated results. Items are sent as the
+// response data and NextCursor, if any, points at the following page.
+type Page struct {
+	Items      interface{}
+	NextCursor string
 }
 
 type errNotFound struct{ error }
@@ -111,7 +128,21 @@ func ErrBadRequest(err error) error {
 	return errBadRequest{err}
 }
 
-func errorStatusCode(err error) int {
+type errConflict struct{ error }
+
+func ErrConflict(err error) error {
+	return errConflict{err}
+}
+
+type errPayloadTooLarge struct{ error }
+
+func ErrPayloadTooLarge(err error) error {
+	return errPayloadTooLarge{err}
+}
+
+// ErrorStatusCode returns the HTTP status code of an error returned by API
+// functions.
+func ErrorStatusCode(err error) int {
 	switch err {
 	case context.Canceled:
 		return http.StatusBadRequest
@@ -123,6 +154,10 @@ func errorStatusCode(err error) int {
 		return http.StatusNotFound
 	case errBadRequest:
 		return http.StatusBadRequest
+	case errConflict:
+		return http.StatusConflict
+	case errPayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
 	default:
 		return http.StatusInternalServerError
 	}
@@ -72,6 +72,44 @@ func TestRequestResponseMiddleware(t *testing.T) {
 		assert.Contains(t, string(data), `{"status":"error","error":"not found"}`)
 	})
 
+	t.Run("Conflict", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+		req = req.WithContext(user.InjectOrgID(req.Context(), "mytenant"))
+
+		requestMiddlware(func(tenant string, req *http.Request) (interface{}, error) {
+			assert.Equal(t, "mytenant", tenant)
+			return nil, ErrConflict(errors.New("conflict"))
+		})(w, req)
+
+		res := w.Result()
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+		assert.Contains(t, string(data), `{"status":"error","error":"conflict"}`)
+	})
+
+	t.Run("PayloadTooLarge", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+		req = req.WithContext(user.InjectOrgID(req.Context(), "mytenant"))
+
+		requestMiddlware(func(tenant string, req *http.Request) (interface{}, error) {
+			assert.Equal(t, "mytenant", tenant)
+			return nil, ErrPayloadTooLarge(errors.New("payload too large"))
+		})(w, req)
+
+		res := w.Result()
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+		assert.Contains(t, string(data), `{"status":"error","error":"payload too large"}`)
+	})
+
 	t.Run("InternalServerError", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/hello", nil)
@@ -91,6 +129,25 @@ func TestRequestResponseMiddleware(t *testing.T) {
 		assert.Contains(t, string(data), `{"status":"error","error":"internal server error"}`)
 	})
 
+	t.Run("ErrorWithBody", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+		req = req.WithContext(user.InjectOrgID(req.Context(), "mytenant"))
+
+		requestMiddlware(func(tenant string, req *http.Request) (interface{}, error) {
+			assert.Equal(t, "mytenant", tenant)
+			return map[string]interface{}{"saved": 1}, ErrConflict(errors.New("conflict"))
+		})(w, req)
+
+		res := w.Result()
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+		assert.Contains(t, string(data), `{"status":"error","data":{"saved":1},"error":"conflict"}`)
+	})
+
 	t.Run("SuccessWithBody", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/hello", nil)
@@ -110,6 +167,25 @@ func TestRequestResponseMiddleware(t *testing.T) {
 		assert.Contains(t, string(data), `{"status":"success","data":{"hello":"world"}}`)
 	})
 
+	t.Run("SuccessWithPage", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+		req = req.WithContext(user.InjectOrgID(req.Context(), "mytenant"))
+
+		requestMiddlware(func(tenant string, req *http.Request) (interface{}, error) {
+			assert.Equal(t, "mytenant", tenant)
+			return Page{Items: []string{"hello", "world"}, NextCursor: "abc"}, nil
+		})(w, req)
+
+		res := w.Result()
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, string(data), `{"status":"success","data":["hello","world"],"next_cursor":"abc"}`)
+	})
+
 	t.Run("SuccessWithoutBody", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/hello", nil)
@@ -1,11 +1,16 @@
 package model
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
+
+	"github.com/grafana/ai-training-o11y/ai-training-api/jsonpatch"
 )
 
 // The database model used to track Group information.
@@ -29,14 +34,62 @@ type Group struct {
 	Processes []Process `json:"processes" gorm:"foreignKey:GroupID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 
 	// Metadata for the group.
-	// links to HF, GH repos, DVC checkpoints, hyperparameters - can be in dozens, rarely hundreds
-	// TODO: cap at 1024?
+	// links to HF, GH repos, DVC checkpoints, hyperparameters - can be in dozens, rarely hundreds,
+	// so it is capped at MaxGroupMetadataKeys flattened keys.
 	Metadata datatypes.JSON `json:"metadata"`
 }
 
+// MaxGroupMetadataKeys is the maximum number of flattened keys in the metadata
+// of a group.
+const MaxGroupMetadataKeys = 1024
+
+// ErrGroupMetadataTooLarge is returned when the metadata of a group would
+// exceed MaxGroupMetadataKeys.
+type ErrGroupMetadataTooLarge struct {
+	Keys int
+}
+
+func (e ErrGroupMetadataTooLarge) Error() string {
+	return fmt.Sprintf("group metadata has %d keys, at most %d are allowed", e.Keys, MaxGroupMetadataKeys)
+}
+
+// MergeMetadata merges patch into the metadata of the group as an RFC 7386
+// merge patch: null members delete keys, objects are merged recursively and
+// anything else replaces the existing value.
+func (t *Group) MergeMetadata(patch map[string]interface{}) error {
+	doc := map[string]interface{}{}
+	if len(t.Metadata) > 0 {
+		d := json.NewDecoder(bytes.NewReader(t.Metadata))
+		d.UseNumber()
+		var current interface{}
+		if err := d.Decode(&current); err != nil {
+			return fmt.Errorf("error decoding group metadata: %w", err)
+		}
+		// Metadata that is not an object is replaced.
+		if m, ok := current.(map[string]interface{}); ok {
+			doc = m
+		}
+	}
+
+	doc = jsonpatch.MergePatch(doc, patch).(map[string]interface{})
+	if n := len(FlattenMetadata(doc)); n > MaxGroupMetadataKeys {
+		return ErrGroupMetadataTooLarge{Keys: n}
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("error encoding group metadata: %w", err)
+	}
+	t.Metadata = datatypes.JSON(b)
+	return nil
+}
+
 // Uodate StartTime and EndTime based on Start and End time of the processes.
+// Both are cleared when the group has no processes.
 func (t *Group) UpdateTimes(processes []Process) {
 	if len(processes) == 0 {
+		t.StartTime = time.Time{}
+		t.EndTime = sql.NullTime{}
 		return
 	}
 
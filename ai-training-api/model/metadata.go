@@ -2,13 +2,37 @@ package model
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 )
 
+// Metadata value types.
+const (
+	MetadataTypeString = "string"
+	MetadataTypeInt    = "int"
+	MetadataTypeFloat  = "float"
+	// Numbers that neither fit in an int64 nor a float64, stored as their
+	// decimal text.
+	MetadataTypeNumber = "number"
+	MetadataTypeBool   = "bool"
+	MetadataTypeNull   = "null"
+	// An empty object. Non-empty objects are only stored through their leaves.
+	MetadataTypeObject = "object"
+	// Marks an array, the value is its length. The elements are stored
+	// under the array's key followed by their index.
+	MetadataTypeArray   = "array"
+	MetadataTypeUnknown = "unknown"
+)
+
+// NumericMetadataTypes are the types whose values are stored in Number.
+var NumericMetadataTypes = []string{MetadataTypeInt, MetadataTypeFloat, MetadataTypeNumber}
+
 // MetadataKV is the database model used to track metadata information.
 // This is used to flatten JSON metadata into a key-value pair and index
 // it for search.
@@ -21,47 +45,162 @@ type MetadataKV struct {
 	Value []byte `json:"value"`
 	// Type is the type of the metadata value.
 	Type string `json:"type"`
+	// Number is the value of numeric metadata as a float64, so that numeric
+	// values can be compared in SQL. It is nil for other types.
+	Number *float64 `json:"-"`
 
 	// Process ID is the UUID of the process to which the metadata belongs.
 	// Its the foreign key to the Process table.
 	ProcessID uuid.UUID `json:"process_id"`
 }
 
+// NewMetadataKV returns the metadata entry storing value under key.
+func NewMetadataKV(tenantID string, processID uuid.UUID, key string, value interface{}) MetadataKV {
+	valueType, valueBytes := MarshalMetadataValue(value)
+	return MetadataKV{
+		TenantID:  tenantID,
+		Key:       key,
+		Value:     valueBytes,
+		Type:      valueType,
+		Number:    metadataNumber(valueBytes, valueType),
+		ProcessID: processID,
+	}
+}
+
+// metadataNumber returns the numeric value of number metadata, or nil if the
+// value is not a number or cannot be represented as a float64.
+func metadataNumber(value []byte, valueType string) *float64 {
+	var f float64
+	switch valueType {
+	case MetadataTypeInt:
+		i, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return nil
+		}
+		f = float64(i)
+	case MetadataTypeFloat:
+		if len(value) != 8 {
+			return nil
+		}
+		f = math.Float64frombits(binary.BigEndian.Uint64(value))
+	case MetadataTypeNumber:
+		var err error
+		f, err = strconv.ParseFloat(string(value), 64)
+		if err != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return &f
+}
+
+// BackfillNumber sets Number from the stored value. It returns false if the
+// entry does not hold a number.
+func (m *MetadataKV) BackfillNumber() bool {
+	m.Number = metadataNumber(m.Value, m.Type)
+	return m.Number != nil
+}
+
+// MarshalMetadataValue returns the type and the stored representation of a
+// metadata value. JSON numbers should be passed as json.Number so that they
+// are stored without losing precision.
 func MarshalMetadataValue(value interface{}) (string, []byte) {
 	switch v := value.(type) {
+	case nil:
+		return MetadataTypeNull, []byte{}
 	case string:
-		return "string", []byte(v)
+		return MetadataTypeString, []byte(v)
 	case int:
-		return "int", []byte(fmt.Sprintf("%d", v))
+		return MetadataTypeInt, []byte(strconv.FormatInt(int64(v), 10))
+	case int64:
+		return MetadataTypeInt, []byte(strconv.FormatInt(v, 10))
+	case json.Number:
+		return marshalJSONNumber(v)
 	case float64:
 		// https://stackoverflow.com/a/55436758
-		if value.(float64) == float64(int64(value.(float64))) {
-			return "int", []byte(fmt.Sprintf("%d", int64(value.(float64))))
+		if v >= math.MinInt64 && v < math.MaxInt64 && v == float64(int64(v)) {
+			return MetadataTypeInt, []byte(strconv.FormatInt(int64(v), 10))
 		}
-
-		bits := math.Float64bits(v)
-		byteArr := make([]byte, 8)
-		binary.BigEndian.PutUint64(byteArr, bits)
-		return "float", byteArr
+		return MetadataTypeFloat, marshalFloat(v)
 	case bool:
-		return "bool", []byte(strconv.FormatBool(v))
+		return MetadataTypeBool, []byte(strconv.FormatBool(v))
+	case map[string]interface{}:
+		return MetadataTypeObject, []byte("{}")
+	case []interface{}:
+		return MetadataTypeArray, []byte(strconv.Itoa(len(v)))
 	default:
-		return "unknown", nil
+		return MetadataTypeUnknown, nil
+	}
+}
+
+// marshalJSONNumber stores integers that fit in an int64 as int and other
+// numbers as float if they survive the round-trip through a float64. Anything
+// else is kept as its decimal text.
+func marshalJSONNumber(n json.Number) (string, []byte) {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return MetadataTypeInt, []byte(s)
+		}
+		return MetadataTypeNumber, []byte(s)
 	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return MetadataTypeNumber, []byte(s)
+	}
+	// The float is lossless if its shortest representation is the same
+	// decimal number as the one we were given.
+	given, ok := new(big.Rat).SetString(s)
+	shortest, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	if !ok || given.Cmp(shortest) != 0 {
+		return MetadataTypeNumber, []byte(s)
+	}
+	return MetadataTypeFloat, marshalFloat(f)
 }
 
+func marshalFloat(f float64) []byte {
+	byteArr := make([]byte, 8)
+	binary.BigEndian.PutUint64(byteArr, math.Float64bits(f))
+	return byteArr
+}
+
+// UnmarshalMetadataValue decodes a stored metadata value. Ints are returned as
+// int64, arbitrary precision numbers as json.Number and array markers as an
+// array of nulls of the stored length.
 func UnmarshalMetadataValue(value []byte, valueType string) (interface{}, error) {
 	switch valueType {
-	case "string":
+	case MetadataTypeString:
 		return string(value), nil
-	case "int":
-		return strconv.Atoi(string(value))
-	case "float":
+	case MetadataTypeInt:
+		return strconv.ParseInt(string(value), 10, 64)
+	case MetadataTypeFloat:
+		if len(value) != 8 {
+			return nil, fmt.Errorf("invalid float value of %d bytes", len(value))
+		}
 		bits := binary.BigEndian.Uint64(value)
 		float := math.Float64frombits(bits)
 		return float, nil
-	case "bool":
+	case MetadataTypeNumber:
+		return json.Number(value), nil
+	case MetadataTypeBool:
 		return strconv.ParseBool(string(value))
+	// Before null was supported it was stored as unknown, which nothing else
+	// coming from JSON could end up as.
+	case MetadataTypeNull, MetadataTypeUnknown:
+		return nil, nil
+	case MetadataTypeObject:
+		return map[string]interface{}{}, nil
+	case MetadataTypeArray:
+		n, err := strconv.Atoi(string(value))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid array length: %q", value)
+		}
+		return make([]interface{}, n), nil
 	default:
 		return nil, fmt.Errorf("unknown type: %s", valueType)
 	}
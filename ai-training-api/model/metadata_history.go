@@ -0,0 +1,81 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Metadata sources recorded in the history when the client does not name
+// one.
+const (
+	MetadataSourceRegister = "register"
+	MetadataSourceUpdate   = "update"
+	// Metadata written before its history was recorded.
+	MetadataSourceBackfill = "backfill"
+)
+
+// MetadataHistory is a single write of a metadata key. Every write appends a
+// row, so that the changes to a key can be audited and the metadata of a
+// process can be rebuilt as of any point in time.
+type MetadataHistory struct {
+	// Version orders the writes, it increases with every write.
+	Version uint64 `json:"version" gorm:"primaryKey;autoIncrement"`
+
+	TenantID  string    `json:"tenant_id" gorm:"index:idx_metadata_history_process"`
+	ProcessID uuid.UUID `json:"process_id" gorm:"type:char(36);index:idx_metadata_history_process"`
+	Key       string    `json:"key"`
+	// Value and Type are those of the written MetadataKV. They are empty
+	// when the key was deleted.
+	Value   []byte `json:"value"`
+	Type    string `json:"type"`
+	Deleted bool   `json:"deleted"`
+	// Source describes who wrote the value, e.g. "register", "update" or a
+	// name supplied by the client.
+	Source string `json:"source"`
+	// Time is when the server received the write.
+	Time time.Time `json:"time"`
+}
+
+// NewMetadataHistory returns the history entry recording that kv was written.
+func NewMetadataHistory(kv MetadataKV, source string, now time.Time) MetadataHistory {
+	return MetadataHistory{
+		TenantID:  kv.TenantID,
+		ProcessID: kv.ProcessID,
+		Key:       kv.Key,
+		Value:     kv.Value,
+		Type:      kv.Type,
+		Source:    source,
+		Time:      now,
+	}
+}
+
+// MetadataAsOf replays history entries, ordered by version, and returns the
+// metadata they leave behind.
+func MetadataAsOf(history []MetadataHistory) []MetadataKV {
+	latest := map[string]MetadataHistory{}
+	var keys []string
+	for _, h := range history {
+		if _, ok := latest[h.Key]; !ok {
+			keys = append(keys, h.Key)
+		}
+		latest[h.Key] = h
+	}
+
+	var rows []MetadataKV
+	for _, key := range keys {
+		h := latest[key]
+		if h.Deleted {
+			continue
+		}
+		rows = append(rows, MetadataKV{
+			TenantID:  h.TenantID,
+			Key:       h.Key,
+			Value:     h.Value,
+			Type:      h.Type,
+			Number:    metadataNumber(h.Value, h.Type),
+			ProcessID: h.ProcessID,
+		})
+	}
+	return rows
+}
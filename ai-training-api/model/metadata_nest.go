@@ -0,0 +1,177 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EscapeMetadataKeySegment escapes the dots and backslashes of a single object
+// key so that it can be joined into a flattened metadata key.
+func EscapeMetadataKeySegment(segment string) string {
+	return strings.NewReplacer(`\`, `\\`, `.`, `\.`).Replace(segment)
+}
+
+// JoinMetadataKey joins a flattened metadata key with a child segment.
+func JoinMetadataKey(prefix, segment string) string {
+	segment = EscapeMetadataKeySegment(segment)
+	if prefix == "" {
+		return segment
+	}
+	return prefix + "." + segment
+}
+
+// SplitMetadataKey splits a flattened metadata key into its unescaped
+// segments.
+func SplitMetadataKey(key string) []string {
+	var segments []string
+	var sb strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case '\\':
+			if i+1 < len(key) {
+				i++
+			}
+			sb.WriteByte(key[i])
+		case '.':
+			segments = append(segments, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(key[i])
+		}
+	}
+	return append(segments, sb.String())
+}
+
+// FlattenMetadata flattens a JSON document into dot separated keys. Arrays are
+// stored as a marker holding their length plus one key per element, and empty
+// objects as a marker of their own, so that NestMetadata can rebuild the
+// original document.
+func FlattenMetadata(doc map[string]interface{}) map[string]interface{} {
+	flat := map[string]interface{}{}
+	for k, v := range doc {
+		flattenMetadataValue(flat, JoinMetadataKey("", k), v)
+	}
+	return flat
+}
+
+func flattenMetadataValue(flat map[string]interface{}, key string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			flat[key] = v
+			return
+		}
+		for k, child := range v {
+			flattenMetadataValue(flat, JoinMetadataKey(key, k), child)
+		}
+	case []interface{}:
+		flat[key] = v
+		for i, child := range v {
+			flattenMetadataValue(flat, JoinMetadataKey(key, strconv.Itoa(i)), child)
+		}
+	default:
+		flat[key] = v
+	}
+}
+
+// metadataNode is a node of a metadata document being rebuilt.
+type metadataNode struct {
+	children map[string]*metadataNode
+	// Set for array markers and empty objects.
+	isArray  bool
+	isObject bool
+	arrayLen int
+	// The value of scalar leaves.
+	value interface{}
+}
+
+func (n *metadataNode) child(segment string) *metadataNode {
+	if n.children == nil {
+		n.children = map[string]*metadataNode{}
+	}
+	c, ok := n.children[segment]
+	if !ok {
+		c = &metadataNode{}
+		n.children[segment] = c
+	}
+	return c
+}
+
+// NestMetadata rebuilds the JSON document from its flattened metadata. If a
+// key holds both a scalar and nested keys, which can only happen for metadata
+// written before it was stored losslessly, the nested keys win.
+func NestMetadata(rows []MetadataKV) (map[string]interface{}, error) {
+	root := &metadataNode{isObject: true}
+	for _, row := range rows {
+		value, err := UnmarshalMetadataValue(row.Value, row.Type)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding metadata %q: %w", row.Key, err)
+		}
+
+		n := root
+		for _, segment := range SplitMetadataKey(row.Key) {
+			n = n.child(segment)
+		}
+		switch row.Type {
+		case MetadataTypeArray:
+			n.isArray = true
+			n.arrayLen = len(value.([]interface{}))
+		case MetadataTypeObject:
+			n.isObject = true
+		case MetadataTypeFloat:
+			n.value = floatJSONNumber(value.(float64))
+		default:
+			n.value = value
+		}
+	}
+
+	doc, _ := root.build().(map[string]interface{})
+	return doc, nil
+}
+
+// floatJSONNumber formats a float so that it stays a float when decoded,
+// e.g. 1.0 rather than 1. JSON cannot represent NaN and infinities, they
+// become null.
+func floatJSONNumber(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return json.Number(s)
+}
+
+func (n *metadataNode) build() interface{} {
+	if n.isArray {
+		if arr, ok := n.buildArray(); ok {
+			return arr
+		}
+	}
+	if len(n.children) > 0 || n.isObject || n.isArray {
+		obj := make(map[string]interface{}, len(n.children))
+		for k, c := range n.children {
+			obj[k] = c.build()
+		}
+		return obj
+	}
+	return n.value
+}
+
+// buildArray builds an array from children keyed by their index. It returns
+// false if any child is not an index within the array's length.
+func (n *metadataNode) buildArray() ([]interface{}, bool) {
+	arr := make([]interface{}, n.arrayLen)
+	for k, c := range n.children {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= n.arrayLen || strconv.Itoa(i) != k {
+			return nil, false
+		}
+		arr[i] = c.build()
+	}
+	return arr, true
+}
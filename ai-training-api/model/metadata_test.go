@@ -0,0 +1,102 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalMetadataValue(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        interface{}
+		expectedType string
+		expected     interface{}
+	}{
+		{name: "null", value: nil, expectedType: MetadataTypeNull, expected: nil},
+		{name: "string", value: "adam", expectedType: MetadataTypeString, expected: "adam"},
+		{name: "bool", value: true, expectedType: MetadataTypeBool, expected: true},
+		{name: "int", value: json.Number("12"), expectedType: MetadataTypeInt, expected: int64(12)},
+		{name: "max int64", value: json.Number("9223372036854775807"), expectedType: MetadataTypeInt, expected: int64(9223372036854775807)},
+		{name: "beyond int64", value: json.Number("9223372036854775808"), expectedType: MetadataTypeNumber, expected: json.Number("9223372036854775808")},
+		{name: "float", value: json.Number("0.001"), expectedType: MetadataTypeFloat, expected: 0.001},
+		{name: "integral float", value: json.Number("1.0"), expectedType: MetadataTypeFloat, expected: 1.0},
+		{name: "beyond float64 precision", value: json.Number("0.10000000000000000001"), expectedType: MetadataTypeNumber, expected: json.Number("0.10000000000000000001")},
+		{name: "beyond float64 range", value: json.Number("1e400"), expectedType: MetadataTypeNumber, expected: json.Number("1e400")},
+		{name: "legacy float64", value: 2.0, expectedType: MetadataTypeInt, expected: int64(2)},
+		{name: "empty object", value: map[string]interface{}{}, expectedType: MetadataTypeObject, expected: map[string]interface{}{}},
+		{name: "array", value: []interface{}{1, 2}, expectedType: MetadataTypeArray, expected: []interface{}{nil, nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valueType, value := MarshalMetadataValue(tt.value)
+			assert.Equal(t, tt.expectedType, valueType)
+
+			decoded, err := UnmarshalMetadataValue(value, valueType)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, decoded)
+		})
+	}
+}
+
+func TestSplitMetadataKey(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "0"}, SplitMetadataKey("a.b.0"))
+	assert.Equal(t, []string{"a.b", `c\`}, SplitMetadataKey(JoinMetadataKey(JoinMetadataKey("", "a.b"), `c\`)))
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	doc := `{
+		"optimizer": "adam",
+		"lr": 0.001,
+		"warmup": 1.0,
+		"seed": 9223372036854775807,
+		"tokens": 123456789012345678901234567890,
+		"tiny": 1e-400,
+		"checkpoint": null,
+		"use_amp": false,
+		"empty_object": {},
+		"empty_array": [],
+		"model": {"layers": 12, "dims": [512, 1024, {"heads": 8}], "tags": [[], null]},
+		"dotted.key": {"x": "y"}
+	}`
+
+	var original map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewBufferString(doc))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&original))
+
+	processID := uuid.New()
+	var rows []MetadataKV
+	for k, v := range FlattenMetadata(original) {
+		rows = append(rows, NewMetadataKV("tenant", processID, k, v))
+	}
+
+	nested, err := NestMetadata(rows)
+	require.NoError(t, err)
+
+	expected, err := json.Marshal(original)
+	require.NoError(t, err)
+	actual, err := json.Marshal(nested)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestNestLegacyMetadata(t *testing.T) {
+	// Metadata written before arrays were marked is nested into objects.
+	rows := []MetadataKV{
+		{Key: "dims.0", Type: "int", Value: []byte("512")},
+		{Key: "dims.1", Type: "int", Value: []byte("1024")},
+		{Key: "checkpoint", Type: "unknown"},
+	}
+	nested, err := NestMetadata(rows)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"dims":       map[string]interface{}{"0": int64(512), "1": int64(1024)},
+		"checkpoint": nil,
+	}, nested)
+}
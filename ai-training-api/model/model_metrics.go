@@ -1,9 +1,19 @@
 package model
 
 import (
+	"database/sql"
+	"math"
+
 	"github.com/google/uuid"
 )
 
+// Values of ModelMetrics.NonFinite.
+const (
+	MetricValueNaN    = "nan"
+	MetricValuePosInf = "+inf"
+	MetricValueNegInf = "-inf"
+)
+
 // StackID is what user we are using
 // ProcessID is the process sending metrics by uuid
 // MetricName is what metric we are logging (e.g., accuracy, loss)
@@ -12,12 +22,76 @@ import (
 // data for the same metric and step in one panel
 // Step is the step number, which goes on the x-axis, and MetricValue is the y-value.
 type ModelMetrics struct {
-	TenantID    string    `json:"stack_id" gorm:"not null;primaryKey"`
-	ProcessID   uuid.UUID `json:"process_id" gorm:"type:char(36);not null;primaryKey;foreignKey:ProcessID;references:ID"` // Foreign key
-	MetricName  string    `json:"metric_name" gorm:"size:32;not null;primaryKey"`
-	StepName    string    `json:"step_name" gorm:"size:32;not null;primaryKey"`
-	Step        uint32    `json:"step" gorm:"not null;primaryKey"`
-	MetricValue string    `json:"metric_value" gorm:"size:64;not null"`
+	TenantID   string    `json:"stack_id" gorm:"not null;primaryKey"`
+	ProcessID  uuid.UUID `json:"process_id" gorm:"type:char(36);not null;primaryKey;foreignKey:ProcessID;references:ID"` // Foreign key
+	MetricName string    `json:"metric_name" gorm:"size:32;not null;primaryKey"`
+	StepName   string    `json:"step_name" gorm:"size:32;not null;primaryKey"`
+	Step       uint32    `json:"step" gorm:"not null;primaryKey"`
+	// Neither SQLite nor MySQL can store NaN and infinities in a double
+	// column, so they are stored as NULL and NonFinite tells which one it
+	// is. SQL aggregates skip them. Use SetValue and Float64 to access the
+	// value.
+	MetricValue sql.NullFloat64 `json:"metric_value" gorm:"type:double"`
+	NonFinite   string          `json:"non_finite,omitempty" gorm:"size:4;not null;default:''"`
+	// Timestamp is the wall-clock time of the point as sent by the client, or
+	// when the server received it. It is NULL for points stored before
+	// timestamps were recorded.
+	Timestamp sql.NullTime `json:"timestamp"`
+	// Axes are the positions of the point on other axes than its step, see
+	// MetricAxis.
+	Axes map[string]float64 `json:"axes,omitempty" gorm:"-"`
 
 	Process Process `gorm:"foreignKey:ProcessID;references:ID"` // Relationship definition
 }
+
+// MetricAxis is the position of a logged point on an additional x-axis, e.g.
+// the epoch and the tokens seen of a point logged by step. The metric values
+// are only stored once, under the StepName and Step of the point, and can be
+// plotted against any of its axes.
+type MetricAxis struct {
+	TenantID  string    `json:"stack_id" gorm:"not null;primaryKey"`
+	ProcessID uuid.UUID `json:"process_id" gorm:"type:char(36);not null;primaryKey"`
+	StepName  string    `json:"step_name" gorm:"size:32;not null;primaryKey"`
+	Step      uint32    `json:"step" gorm:"not null;primaryKey"`
+	AxisName  string    `json:"axis_name" gorm:"size:32;not null;primaryKey"`
+	Value     float64   `json:"value" gorm:"type:double;not null"`
+}
+
+// TableName overrides the table name gorm would infer, metric_axis.
+func (MetricAxis) TableName() string { return "metric_axes" }
+
+// SetValue sets the metric value.
+func (m *ModelMetrics) SetValue(f float64) {
+	m.MetricValue, m.NonFinite = SplitMetricValue(f)
+}
+
+// Float64 returns the metric value.
+func (m *ModelMetrics) Float64() float64 {
+	return JoinMetricValue(m.MetricValue, m.NonFinite)
+}
+
+// SplitMetricValue returns how a metric value is stored.
+func SplitMetricValue(f float64) (sql.NullFloat64, string) {
+	switch {
+	case math.IsNaN(f):
+		return sql.NullFloat64{}, MetricValueNaN
+	case math.IsInf(f, 1):
+		return sql.NullFloat64{}, MetricValuePosInf
+	case math.IsInf(f, -1):
+		return sql.NullFloat64{}, MetricValueNegInf
+	}
+	return sql.NullFloat64{Float64: f, Valid: true}, ""
+}
+
+// JoinMetricValue returns the metric value stored as value and nonFinite.
+func JoinMetricValue(value sql.NullFloat64, nonFinite string) float64 {
+	switch nonFinite {
+	case MetricValueNaN:
+		return math.NaN()
+	case MetricValuePosInf:
+		return math.Inf(1)
+	case MetricValueNegInf:
+		return math.Inf(-1)
+	}
+	return value.Float64
+}
@@ -2,12 +2,81 @@ package model
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
+// Process states. A process starts out as running and moves to exactly one
+// terminal state once it finishes.
+const (
+	ProcessStatusRunning   = "running"
+	ProcessStatusSucceeded = "succeeded"
+	ProcessStatusFailed    = "failed"
+	ProcessStatusCancelled = "cancelled"
+	ProcessStatusCrashed   = "crashed"
+)
+
+// processStatusAliases maps alternative spellings sent by older clients onto
+// the canonical process states.
+var processStatusAliases = map[string]string{
+	"successful": ProcessStatusSucceeded,
+	"success":    ProcessStatusSucceeded,
+	"canceled":   ProcessStatusCancelled,
+}
+
+// processTransitions lists the states each state is allowed to move to.
+var processTransitions = map[string][]string{
+	ProcessStatusRunning: {
+		ProcessStatusSucceeded,
+		ProcessStatusFailed,
+		ProcessStatusCancelled,
+		ProcessStatusCrashed,
+	},
+}
+
+// ErrInvalidProcessTransition is returned when a process is asked to move to a
+// state that is not reachable from its current state.
+type ErrInvalidProcessTransition struct {
+	From string
+	To   string
+}
+
+func (e ErrInvalidProcessTransition) Error() string {
+	return fmt.Sprintf("invalid process state transition from %q to %q", e.From, e.To)
+}
+
+// NormalizeProcessStatus returns the canonical name of a process state, or an
+// error if the state is unknown.
+func NormalizeProcessStatus(status string) (string, error) {
+	if alias, ok := processStatusAliases[status]; ok {
+		status = alias
+	}
+	switch status {
+	case ProcessStatusRunning,
+		ProcessStatusSucceeded,
+		ProcessStatusFailed,
+		ProcessStatusCancelled,
+		ProcessStatusCrashed:
+		return status, nil
+	}
+	return "", fmt.Errorf("unknown process state: %q", status)
+}
+
+// IsTerminalProcessStatus reports whether no further transitions are allowed
+// out of the given state.
+func IsTerminalProcessStatus(status string) bool {
+	switch status {
+	case ProcessStatusSucceeded,
+		ProcessStatusFailed,
+		ProcessStatusCancelled,
+		ProcessStatusCrashed:
+		return true
+	}
+	return false
+}
+
 type Process struct {
 	// UUID generated for the process.
 	ID uuid.UUID `json:"process_uuid" gorm:"primarykey;type:char(36)" validate:"isdefault"`
@@ -20,6 +89,17 @@ type Process struct {
 	// End time. Should be nullable to allow for processes that are still running.
 	EndTime sql.NullTime `json:"end_time"`
 
+	// Last heartbeat time. Running processes whose last heartbeat is older
+	// than the tenant's heartbeat timeout are marked as crashed.
+	LastHeartbeatAt sql.NullTime `json:"last_heartbeat_at"`
+
+	// Exit reason is a short machine readable reason reported with the
+	// terminal state (e.g. "oom", "keyboard_interrupt").
+	ExitReason string `json:"exit_reason,omitempty"`
+	// Exit message is a free-form human readable message reported with the
+	// terminal state.
+	ExitMessage string `json:"exit_message,omitempty"`
+
 	// Group ID is the UUID of the group to which the process belongs.
 	// Its the foreign key to the Group table. It is a pointer to allow for null values.
 	GroupID *uuid.UUID `json:"group_uuid" gorm:"type:char(36)"`
@@ -33,16 +113,48 @@ type Process struct {
 	Metadata []MetadataKV `json:"metadata" gorm:"-"`
 }
 
-// Add an AfterFind hook that updates EndTime if the StartTime is older than
-// an hour. This is to handle the case where the process is started but never
-// marked complete (e.g. due to a crash). The EndTime should be set to the
-// StartTime + 1 hour.
-func (p *Process) AfterFind(tx *gorm.DB) error {
-	tx.Logger.Info(tx.Statement.Context, "AfterFind hook called to update EndTime")
-	if p.EndTime.Time.IsZero() && time.Since(p.StartTime) > time.Hour {
-		p.EndTime.Time = p.StartTime.Add(time.Hour)
-		p.EndTime.Valid = true
-		return tx.Save(p).Error
+// Transition moves the process to the given state, validating it against the
+// process lifecycle. Terminal states set EndTime to now. Moving to the state
+// the process is already in is a no-op so that clients can safely retry.
+func (p *Process) Transition(to string, now time.Time) error {
+	to, err := NormalizeProcessStatus(to)
+	if err != nil {
+		return err
+	}
+
+	// Processes created before statuses were tracked are treated as running.
+	from := p.Status
+	if from == "" {
+		from = ProcessStatusRunning
+	}
+
+	if from == to {
+		return nil
+	}
+
+	allowed := false
+	for _, next := range processTransitions[from] {
+		if next == to {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return ErrInvalidProcessTransition{From: from, To: to}
+	}
+
+	p.Status = to
+	if IsTerminalProcessStatus(to) {
+		p.EndTime = sql.NullTime{Time: now, Valid: true}
 	}
 	return nil
 }
+
+// LastSeen returns the last time the process was known to be alive: its last
+// heartbeat, or its start time if it never sent one.
+func (p *Process) LastSeen() time.Time {
+	if p.LastHeartbeatAt.Valid && p.LastHeartbeatAt.Time.After(p.StartTime) {
+		return p.LastHeartbeatAt.Time
+	}
+	return p.StartTime
+}
@@ -0,0 +1,5 @@
+// Package prompb contains the protobuf messages of Prometheus remote-write
+// requests.
+package prompb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative remote.proto
@@ -63,19 +63,24 @@ export const GraphsView: React.FC<GraphsProps> = ({ rows }) => {
     const unit = panelData.series[0].name;
     const fields = panelData.series.map((s: any): DataFrame[] => {
       s.values = [
-        ...s.values.map((v: string | undefined) => {
+        ...s.values.map((v: number | string | undefined) => {
           if (v === undefined || v === null) {
             return undefined
           }
-          return parseFloat(v)
+          return typeof v === 'number' ? v : parseFloat(v)
       })
       ]
+      // NaN and infinities are sent as null, with their indices in entities.
+      s.entities?.NaN?.forEach((i: number) => { s.values[i] = NaN });
+      s.entities?.Inf?.forEach((i: number) => { s.values[i] = Infinity });
+      s.entities?.NegInf?.forEach((i: number) => { s.values[i] = -Infinity });
       return {
         ...s,
         config: {
           color: {
             mode: 'fixed',
-            fixedColor: colors.get(s.name) || palette[0],
+            // Smoothed series have the color of the series they smooth.
+            fixedColor: colors.get(s.labels?.smoothed ?? s.name) || palette[0],
           },
         }
       }
@@ -122,13 +122,19 @@ class Client:
             }
             self.step += 1
 
+        # The first entry is the step of the point, any others are extra
+        # axes the metrics can be plotted against.
         step_name, step_value = next(iter(x_axis.items()))
+        axes = {name: value for name, value in x_axis.items() if name != step_name}
 
         json_data = [{
             "step_name": step_name,
             "step_value": step_value,
+            "timestamp": time.time(),
             "metrics": log
         }]
+        if axes:
+            json_data[0]["axes"] = axes
 
         url = f'{self.url.geturl()}/api/v1/process/{self.process_uuid}/model-metrics'
 
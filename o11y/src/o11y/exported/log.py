@@ -7,14 +7,15 @@ from .. import logger
 def log(
         log_data: Dict[str, Union[int, float, Decimal]],
         *,
-        x_axis: Optional[Dict[str, int]] = None
+        x_axis: Optional[Dict[str, Union[int, float]]] = None
         ) -> bool:
     """
     Sends a log to the Loki server.
 
     Args:
         log (Dict[str, Union[int, float, Decimal]]): The log message as a dictionary with string keys and numeric values.
-        x_axis (Optional[Dict[str, Union[int, float]]], optional): A single-item dictionary representing the x-axis. Defaults to None.
+        x_axis (Optional[Dict[str, Union[int, float]]], optional): A dictionary representing the x-axes. The first item is the step
+            and must be an integer, any others are extra axes the metrics can also be plotted against, e.g. the epoch. Defaults to None.
 
     Returns:
         bool: True if the log was sent successfully, False otherwise.
@@ -31,8 +32,8 @@ def log(
     if not x_axis:
         return bool(client.send_model_metrics(log_data))
 
-    if not isinstance(x_axis, dict) or len(x_axis) != 1:
-        logger.error("x_axis must be a dict with one key")
+    if not isinstance(x_axis, dict):
+        logger.error("x_axis must be a dict")
         return False
 
     x_key, x_value = next(iter(x_axis.items()))
@@ -45,4 +46,9 @@ def log(
         logger.error("x_axis key must not be in your metrics, or must have the same value")
         return False
 
+    for axis_key, axis_value in x_axis.items():
+        if not isinstance(axis_key, str) or isinstance(axis_value, bool) or not isinstance(axis_value, (int, float)):
+            logger.error("x_axis must have string keys and numeric values")
+            return False
+
     return bool(client.send_model_metrics(log_data, x_axis=x_axis))
@@ -41,13 +41,19 @@ def test_log_with_invalid_x_axis_not_dict(mock_logger):
     valid_log = {"metric1": 10}
     invalid_x_axis = "not a dict"
     assert log(valid_log, x_axis=invalid_x_axis) is False
-    mock_logger.error.assert_called_once_with("x_axis must be a dict with one key")
+    mock_logger.error.assert_called_once_with("x_axis must be a dict")
 
-def test_log_with_invalid_x_axis_multiple_keys(mock_logger):
+def test_log_with_multiple_x_axes(mock_client):
     valid_log = {"metric1": 10}
-    invalid_x_axis = {"step": 1, "extra": 2}
+    valid_x_axis = {"step": 1, "epoch": 0.5, "tokens_seen": 2048}
+    assert log(valid_log, x_axis=valid_x_axis) is True
+    mock_client.send_model_metrics.assert_called_once_with(valid_log, x_axis=valid_x_axis)
+
+def test_log_with_invalid_extra_x_axis(mock_logger):
+    valid_log = {"metric1": 10}
+    invalid_x_axis = {"step": 1, "epoch": "1"}
     assert log(valid_log, x_axis=invalid_x_axis) is False
-    mock_logger.error.assert_called_once_with("x_axis must be a dict with one key")
+    mock_logger.error.assert_called_once_with("x_axis must have string keys and numeric values")
 
 def test_log_with_invalid_x_axis_non_string_key(mock_logger):
     valid_log = {"metric1": 10}